@@ -0,0 +1,69 @@
+package goeinstein
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+)
+
+// WriteFileAtomic writes data to a temporary file of the same directory and
+// renames it over fileName, so the file has either the old or the new content
+// even if the game crashes while writing. As with os.WriteFile, perm is
+// masked by the umask.
+func WriteFileAtomic(fileName string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(fileName)
+	f, err := createTemp(fileName, perm)
+	if err != nil {
+		return fmt.Errorf("create temp file (dir: %q): %w", dir, err)
+	}
+	tmpName := f.Name()
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmpName, fileName)
+	}
+	if err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("write file (filename: %q): %w", fileName, err)
+	}
+
+	syncDir(dir)
+	return nil
+}
+
+// createTemp creates a new file next to fileName. Unlike os.CreateTemp it
+// creates the file with perm, so the umask is applied.
+func createTemp(fileName string, perm os.FileMode) (*os.File, error) {
+	for i := 0; ; i++ {
+		tmpName := filepath.Join(filepath.Dir(fileName),
+			"."+filepath.Base(fileName)+"."+strconv.FormatUint(uint64(rand.Uint32()), 10)+".tmp")
+		f, err := os.OpenFile(tmpName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+		if os.IsExist(err) && i < 100 {
+			continue
+		}
+		return f, err
+	}
+}
+
+// syncDir makes the rename durable. It is not supported on Windows, where
+// the rename is durable by itself.
+func syncDir(dir string) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	d.Close()
+}
@@ -0,0 +1,75 @@
+package goeinstein
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+//nolint:golint,nosnakecase,stylecheck
+const AUTOSAVE_INTERVAL = 60 * 1000 // milliseconds
+
+func GetAutosaveFileName() string {
+	return filepath.Join(GetProfileDataDir(), "autosave.sav")
+}
+
+func HasAutosave() bool {
+	_, err := os.Stat(GetAutosaveFileName())
+	return err == nil
+}
+
+func RemoveAutosave() {
+	err := os.Remove(GetAutosaveFileName())
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("Error on remove autosave: %v", err)
+	}
+}
+
+// LoadAutosave resumes the last unfinished game. Unlike saved games it keeps
+// the game in the hall of fame if it was there.
+func LoadAutosave() (*Game, error) {
+	g, info, err := loadSavedGame(GetAutosaveFileName())
+	if err != nil {
+		return nil, err
+	}
+	g.hinted = info.Hinted
+	return g, nil
+}
+
+// Autosave writes the game to the autosave file. Errors are only logged as
+// the player cannot do anything with them.
+func (g *Game) Autosave() {
+	g.lastAutosave = sdl.GetTicks64()
+
+	var buf bytes.Buffer
+	err := WriteSaveFile(&buf, msg("autosave"), g)
+	if err != nil {
+		log.Printf("Error on autosave: %v", err)
+		return
+	}
+	err = WriteFileAtomic(GetAutosaveFileName(), buf.Bytes(), 0o644)
+	if err != nil {
+		log.Printf("Error on autosave: %v", err)
+	}
+}
+
+// SaveOnExit keeps an unfinished game in the autosave file and removes the
+// autosave of a finished one.
+func (g *Game) SaveOnExit() {
+	if g.finished {
+		RemoveAutosave()
+	} else {
+		g.Autosave()
+	}
+}
+
+// OnTimer runs the watch and autosaves the game while it is played.
+func (g *Game) OnTimer() {
+	g.watch.OnTimer()
+	if !g.watch.stoped && sdl.GetTicks64()-g.lastAutosave >= AUTOSAVE_INTERVAL {
+		g.Autosave()
+	}
+}
@@ -1,6 +1,7 @@
 package goeinstein
 
 import (
+	"fmt"
 	"io"
 )
 
@@ -51,8 +52,16 @@ func (c *Cell) WriteTo(w io.Writer) {
 	}
 }
 
-func (c *Cell) ReadFrom(r io.Reader) {
+func (c *Cell) ReadFrom(r io.Reader) error {
 	for i := range *c {
-		(*c)[i] = Card(ReadInt(r))
+		v, err := ReadInt(r)
+		if err != nil {
+			return err
+		}
+		if v != 0 && v != i+1 {
+			return fmt.Errorf("wrong cell element %d at %d", v, i)
+		}
+		(*c)[i] = Card(v)
 	}
+	return nil
 }
@@ -1,13 +1,18 @@
 package main
 
 import (
+	"flag"
 	"log"
 
 	"github.com/vkd/goeinstein"
 )
 
 func main() {
+	portable := flag.Bool("portable", false, "keep settings and saves in ./einstein of the working directory")
+	flag.Parse()
+
 	log.Printf("Starting...")
+	goeinstein.SetPortable(*portable)
 	err := goeinstein.Main()
 	if err != nil {
 		log.Fatalf("Error: %v", err)
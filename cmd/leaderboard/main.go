@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/vkd/goeinstein"
+	"github.com/vkd/goeinstein/leaderboard"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	fileName := flag.String("file", "leaderboard.json", "file to keep the boards in, empty to keep them in memory")
+	maxScores := flag.Int("n", 100, "amount of scores kept per board")
+	flag.Parse()
+
+	server, err := leaderboard.NewServer(*maxScores, *fileName)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	// scores are added only if their replays solve the puzzles
+	server.Verify = goeinstein.VerifySubmission
+	log.Printf("Listening on %s", *addr)
+	err = http.ListenAndServe(*addr, server)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
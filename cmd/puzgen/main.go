@@ -0,0 +1,224 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vkd/goeinstein"
+)
+
+func main() {
+	count := flag.Int("n", 10, "amount of puzzles to generate")
+	seed := flag.Int64("seed", time.Now().Unix(), "seed of the first puzzle, next puzzles use seed+1, seed+2, ...")
+	difficulty := flag.String("difficulty", "", "difficulty preset: "+strings.Join(goeinstein.DIFFICULTIES, ", "))
+	weights := flag.String("weights", "", "rule type weights, e.g. near=4,open=0,under=2,direction=4,between=3")
+	minimal := flag.Bool("minimal", false, "search for minimal rule sets")
+	redundancy := flag.Int("redundancy", 0, "amount of redundant rules to keep")
+	quality := flag.Bool("quality", false, "enable quality filters, -quality=false disables them of the preset")
+	verbose := flag.Bool("v", false, "print rules of generated puzzles")
+	verify := flag.Bool("verify", false, "check every puzzle with the bundled SAT solver")
+	dimacsDir := flag.String("dimacs", "", "directory to write puzzles as DIMACS CNF (<seed>.cnf)")
+	mznDir := flag.String("minizinc", "", "directory to write puzzles as MiniZinc models (<seed>.mzn)")
+	jsonDir := flag.String("json", "", "directory to write rules of puzzles as JSON (<seed>.json)")
+	check := flag.Bool("check", false, "check the rules of JSON files given as arguments instead of generating puzzles")
+	flag.Parse()
+
+	if *check {
+		failed := false
+		for _, name := range flag.Args() {
+			err := checkRulesFile(name)
+			if err != nil {
+				log.Printf("Error on %s: %v", name, err)
+				failed = true
+				continue
+			}
+			fmt.Printf("%s: ok\n", name)
+		}
+		if failed {
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfg := goeinstein.NewGenConfig()
+	if *difficulty != "" {
+		known := false
+		for _, d := range goeinstein.DIFFICULTIES {
+			known = known || d == *difficulty
+		}
+		if !known {
+			log.Fatalf("Error: unknown difficulty %q, expected one of %s", *difficulty, strings.Join(goeinstein.DIFFICULTIES, ", "))
+		}
+		cfg = goeinstein.NewGenConfigDifficulty(*difficulty)
+	}
+	// only flags which are set override the preset
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "minimal":
+			cfg.Minimal = *minimal
+		case "redundancy":
+			cfg.Redundancy = *redundancy
+		case "quality":
+			if !*quality {
+				cfg.Quality = nil
+			} else if cfg.Quality == nil {
+				cfg.Quality = goeinstein.NewQualityConfig()
+			}
+		}
+	})
+	if cfg.Quality != nil {
+		cfg.QualityLog = goeinstein.NewQualityLog()
+	}
+	if *weights != "" {
+		err := parseWeights(cfg.Weights, *weights)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
+
+	for i := 0; i < *count; i++ {
+		s := *seed + int64(i)
+		var puzzle goeinstein.SolvedPuzzle
+		var rules goeinstein.Rules
+		redundant, err := goeinstein.GenPuzzleConfig(&puzzle, &rules, rand.New(rand.NewSource(s)), cfg)
+		if err != nil {
+			log.Fatalf("Error on seed %d: %v", s, err)
+		}
+		fmt.Printf("seed=%d rules=%d redundant=%v\n", s, len(rules), redundant)
+		if *verbose {
+			for _, r := range rules {
+				fmt.Printf("\t%s\n", r.GetAsText())
+			}
+		}
+		if *verify {
+			err = verifyPuzzle(&puzzle, rules)
+			if err != nil {
+				log.Fatalf("Error on seed %d: %v", s, err)
+			}
+		}
+		if *dimacsDir != "" {
+			err = writeFile(filepath.Join(*dimacsDir, fmt.Sprintf("%d.cnf", s)), func(w io.Writer) error {
+				cnf, err := goeinstein.EncodeCNF(rules, goeinstein.PUZZLE_SIZE)
+				if err != nil {
+					return err
+				}
+				return cnf.WriteDIMACS(w)
+			})
+			if err != nil {
+				log.Fatalf("Error on seed %d: %v", s, err)
+			}
+		}
+		if *jsonDir != "" {
+			err = writeFile(filepath.Join(*jsonDir, fmt.Sprintf("%d.json", s)), func(w io.Writer) error {
+				bs, err := goeinstein.MarshalRulesJSON(rules)
+				if err != nil {
+					return err
+				}
+				_, err = w.Write(bs)
+				return err
+			})
+			if err != nil {
+				log.Fatalf("Error on seed %d: %v", s, err)
+			}
+		}
+		if *mznDir != "" {
+			err = writeFile(filepath.Join(*mznDir, fmt.Sprintf("%d.mzn", s)), func(w io.Writer) error {
+				return goeinstein.WriteMiniZinc(w, rules, goeinstein.PUZZLE_SIZE)
+			})
+			if err != nil {
+				log.Fatalf("Error on seed %d: %v", s, err)
+			}
+		}
+	}
+
+	if cfg.QualityLog != nil {
+		log.Printf("Rejected:\n%s", cfg.QualityLog)
+	}
+}
+
+func parseWeights(w goeinstein.RuleWeights, s string) error {
+	for _, kv := range strings.Split(s, ",") {
+		tag, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return fmt.Errorf("wrong weight %q: expected tag=value", kv)
+		}
+		tag = strings.TrimSpace(tag)
+		if _, ok = goeinstein.GetRuleType(tag); !ok {
+			return fmt.Errorf("unknown rule type %q", tag)
+		}
+		v, err := strconv.Atoi(value)
+		if err != nil || v < 0 {
+			return fmt.Errorf("wrong weight value %q", kv)
+		}
+		w[tag] = v
+	}
+	return nil
+}
+
+// verifyPuzzle checks that the CNF encoding of rules has the only model and
+// it is the generated puzzle.
+func verifyPuzzle(puzzle *goeinstein.SolvedPuzzle, rules goeinstein.Rules) error {
+	cnf, err := goeinstein.EncodeCNF(rules, goeinstein.PUZZLE_SIZE)
+	if err != nil {
+		return err
+	}
+	lits, ok := goeinstein.SolveCNF(cnf)
+	if !ok {
+		return fmt.Errorf("CNF is unsatisfiable")
+	}
+	solved, err := goeinstein.DecodeCNFSolution(lits)
+	if err != nil {
+		return err
+	}
+	if *solved != *puzzle {
+		return fmt.Errorf("CNF solution differs from the generated puzzle")
+	}
+	if !goeinstein.IsUniqueCNF(cnf) {
+		return fmt.Errorf("CNF has more than one solution")
+	}
+	return nil
+}
+
+// checkRulesFile reads rules written with -json and checks that they have the
+// only solution.
+func checkRulesFile(name string) error {
+	bs, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	rules, err := goeinstein.UnmarshalRulesJSON(bs)
+	if err != nil {
+		return err
+	}
+	cnf, err := goeinstein.EncodeCNF(rules, goeinstein.PUZZLE_SIZE)
+	if err != nil {
+		return err
+	}
+	if _, ok := goeinstein.SolveCNF(cnf); !ok {
+		return fmt.Errorf("rules have no solution")
+	}
+	if !goeinstein.IsUniqueCNF(cnf) {
+		return fmt.Errorf("rules have more than one solution")
+	}
+	return nil
+}
+
+func writeFile(name string, write func(io.Writer) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
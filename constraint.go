@@ -0,0 +1,211 @@
+package goeinstein
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CNFEncoder is implemented by rules which can be written as clauses over
+// the variables returned by CNFVar.
+type CNFEncoder interface {
+	EncodeCNF(size int) [][]int
+}
+
+// MiniZincEncoder is implemented by rules which can be written as a MiniZinc
+// constraint. pos[row, thing] is the 1-based column of the thing of the row.
+type MiniZincEncoder interface {
+	EncodeMiniZinc() string
+}
+
+// CNFVar returns the DIMACS variable which is true if thing is at (col, row)
+// of a size x size puzzle: row*size*size + col*size + thing, thing is
+// 1-based.
+func CNFVar(size, row, col int, thing Card) int {
+	return row*size*size + col*size + int(thing)
+}
+
+// CNF is a puzzle in conjunctive normal form.
+type CNF struct {
+	Size    int
+	Vars    int
+	Clauses [][]int
+	// Comments are written before the problem line.
+	Comments []string
+}
+
+// EncodeCNF translates rules of a size x size puzzle into CNF. The solved
+// puzzle is not needed: every model of the CNF is a solution of the rules.
+func EncodeCNF(rules Rules, size int) (*CNF, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("wrong puzzle size: %d", size)
+	}
+	c := &CNF{
+		Size: size,
+		Vars: size * size * size,
+		Comments: []string{
+			fmt.Sprintf("einstein puzzle %dx%d", size, size),
+			fmt.Sprintf("var = row*%d + col*%d + thing, row and col are 0-based, thing is 1-based", size*size, size),
+		},
+	}
+
+	for row := 0; row < size; row++ {
+		for col := 0; col < size; col++ {
+			c.addExactlyOne(func(i int) int { return CNFVar(size, row, col, Card(i+1)) })
+		}
+		for thing := 1; thing <= size; thing++ {
+			c.addExactlyOne(func(i int) int { return CNFVar(size, row, i, Card(thing)) })
+		}
+	}
+
+	for _, r := range rules {
+		e, ok := r.(CNFEncoder)
+		if !ok {
+			return nil, fmt.Errorf("rule type %q cannot be encoded as CNF", r.GetTag())
+		}
+		c.Comments = append(c.Comments, r.GetTag()+": "+r.GetAsText())
+		for _, cl := range e.EncodeCNF(size) {
+			for _, lit := range cl {
+				if lit == 0 || lit > c.Vars || -lit > c.Vars {
+					return nil, fmt.Errorf("rule %q does not fit the puzzle size %d", r.GetAsText(), size)
+				}
+			}
+			c.Clauses = append(c.Clauses, cl)
+		}
+	}
+	return c, nil
+}
+
+func (c *CNF) addExactlyOne(v func(i int) int) {
+	all := make([]int, 0, c.Size)
+	for i := 0; i < c.Size; i++ {
+		all = append(all, v(i))
+		for j := i + 1; j < c.Size; j++ {
+			c.Clauses = append(c.Clauses, []int{-v(i), -v(j)})
+		}
+	}
+	c.Clauses = append(c.Clauses, all)
+}
+
+func (c *CNF) WriteDIMACS(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, s := range c.Comments {
+		fmt.Fprintf(bw, "c %s\n", s)
+	}
+	fmt.Fprintf(bw, "p cnf %d %d\n", c.Vars, len(c.Clauses))
+	for _, cl := range c.Clauses {
+		for _, lit := range cl {
+			bw.WriteString(strconv.Itoa(lit))
+			bw.WriteByte(' ')
+		}
+		bw.WriteString("0\n")
+	}
+	return bw.Flush()
+}
+
+// ReadDIMACSSolution reads a solver answer in the SAT competition format
+// ("s SATISFIABLE" and "v ..." lines) or in the MiniSat format ("SAT" and
+// a line of literals) and decodes it into a puzzle.
+func ReadDIMACSSolution(r io.Reader) (*SolvedPuzzle, error) {
+	var lits []int
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] == "c" {
+			continue
+		}
+		switch fields[0] {
+		case "s":
+			if len(fields) > 1 && fields[1] != "SATISFIABLE" {
+				return nil, fmt.Errorf("no solution: %s", strings.Join(fields[1:], " "))
+			}
+			continue
+		case "SAT", "SATISFIABLE":
+			continue
+		case "UNSAT", "UNSATISFIABLE":
+			return nil, fmt.Errorf("no solution: %s", fields[0])
+		case "v":
+			fields = fields[1:]
+		}
+		for _, f := range fields {
+			lit, err := strconv.Atoi(f)
+			if err != nil {
+				return nil, fmt.Errorf("wrong literal %q: %w", f, err)
+			}
+			if lit != 0 {
+				lits = append(lits, lit)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read solution: %w", err)
+	}
+	return DecodeCNFSolution(lits)
+}
+
+// DecodeCNFSolution converts true literals of a model of EncodeCNF(rules,
+// PUZZLE_SIZE) into a puzzle. Negative literals are ignored.
+func DecodeCNFSolution(lits []int) (*SolvedPuzzle, error) {
+	const size = PUZZLE_SIZE
+	var p SolvedPuzzle
+	for _, lit := range lits {
+		if lit <= 0 {
+			continue
+		}
+		if lit > size*size*size {
+			return nil, fmt.Errorf("literal %d is out of the puzzle", lit)
+		}
+		v := lit - 1
+		row, col, thing := v/(size*size), v/size%size, Card(v%size+1)
+		if p[row][col] != 0 && p[row][col] != thing {
+			return nil, fmt.Errorf("cell %d of row %d has two things: %d and %d", col+1, row+1, p[row][col], thing)
+		}
+		p[row][col] = thing
+	}
+
+	for row := 0; row < size; row++ {
+		var used [size + 1]bool
+		for col := 0; col < size; col++ {
+			if p[row][col] == 0 {
+				return nil, fmt.Errorf("cell %d of row %d is not assigned", col+1, row+1)
+			}
+			if used[p[row][col]] {
+				return nil, fmt.Errorf("thing %d is used twice in row %d", p[row][col], row+1)
+			}
+			used[p[row][col]] = true
+		}
+	}
+	return &p, nil
+}
+
+// WriteMiniZinc writes rules of a size x size puzzle as a MiniZinc model.
+func WriteMiniZinc(w io.Writer, rules Rules, size int) error {
+	if size < 1 {
+		return fmt.Errorf("wrong puzzle size: %d", size)
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%% einstein puzzle %dx%d\n", size, size)
+	fmt.Fprint(bw, "include \"alldifferent.mzn\";\n\n")
+	fmt.Fprintf(bw, "int: n = %d;\n", size)
+	fmt.Fprint(bw, "% pos[row, thing] is the column of the thing of the row\n")
+	fmt.Fprint(bw, "array[1..n, 1..n] of var 1..n: pos;\n\n")
+	fmt.Fprint(bw, "constraint forall(r in 1..n)(alldifferent([pos[r, t] | t in 1..n]));\n\n")
+	for _, r := range rules {
+		e, ok := r.(MiniZincEncoder)
+		if !ok {
+			return fmt.Errorf("rule type %q cannot be encoded as MiniZinc", r.GetTag())
+		}
+		fmt.Fprintf(bw, "%% %s\n", r.GetAsText())
+		fmt.Fprintf(bw, "constraint %s;\n", e.EncodeMiniZinc())
+	}
+	fmt.Fprint(bw, "\nsolve satisfy;\n\n")
+	fmt.Fprint(bw, "output [show2d(pos)];\n")
+	return bw.Flush()
+}
+
+// mznPos returns the MiniZinc expression of the column of the thing.
+func mznPos(row int, thing Card) string {
+	return fmt.Sprintf("pos[%d, %d]", row+1, thing)
+}
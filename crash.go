@@ -0,0 +1,157 @@
+package goeinstein
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// runningGame is the game whose event loop is running. It is not reset by a
+// defer, so it is still set when the panic reaches Main.
+var runningGame *Game
+
+func GetCrashSaveFileName() string {
+	return filepath.Join(GetProfileDataDir(), "crash.sav")
+}
+
+func HasCrashSave() bool {
+	_, err := os.Stat(GetCrashSaveFileName())
+	return err == nil
+}
+
+func RemoveCrashSave() {
+	err := os.Remove(GetCrashSaveFileName())
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("Error on remove crash save: %v", err)
+	}
+}
+
+// HandleCrash is called with the recovered panic value. It writes the crash
+// report, saves the running game and tells the player about it if the screen
+// still works. It returns the error to be returned from Main.
+func HandleCrash(r interface{}) error {
+	stack := debug.Stack()
+	saveErr := emergencySave()
+
+	reportName, err := writeCrashReport(r, stack, saveErr)
+	if err != nil {
+		log.Printf("Error on write crash report: %v", err)
+		reportName = "-"
+	}
+
+	showCrashMessage()
+	return fmt.Errorf("crash: %v (report: %s)", r, reportName)
+}
+
+// emergencySave writes the running game to the crash save file. The game may
+// be in any state, so a panic while saving is returned as an error.
+func emergencySave() (err error) {
+	g := runningGame
+	if g == nil || g.finished {
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic on save: %v", r)
+		}
+	}()
+
+	var buf bytes.Buffer
+	err = WriteSaveFile(&buf, msg("crashSave"), g)
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(GetCrashSaveFileName(), buf.Bytes(), 0o644)
+}
+
+func writeCrashReport(r interface{}, stack []byte, saveErr error) (string, error) {
+	now := time.Now()
+	fileName := filepath.Join(GetConfigDir(), "crash-"+now.Format("20060102-150405")+".txt")
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Time: %s\n", now.Format(time.RFC3339))
+	fmt.Fprintf(&buf, "Go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&buf, "Panic: %v\n", r)
+
+	buf.WriteString("\nGame:\n")
+	if g := runningGame; g != nil {
+		fmt.Fprintf(&buf, "  seed: %d\n", g.seed)
+		fmt.Fprintf(&buf, "  difficulty: %s\n", g.difficulty)
+		fmt.Fprintf(&buf, "  finished: %v\n", g.finished)
+		switch {
+		case g.finished:
+		case saveErr != nil:
+			fmt.Fprintf(&buf, "  save: %v\n", saveErr)
+		default:
+			fmt.Fprintf(&buf, "  save: %s\n", GetCrashSaveFileName())
+		}
+	} else {
+		buf.WriteString("  none\n")
+	}
+
+	buf.WriteString("\nOptions:\n")
+	if options != nil {
+		for _, s := range options.Settings.GetAll() {
+			fmt.Fprintf(&buf, "  %s: %s\n", s.GetKey(), s)
+		}
+	} else {
+		buf.WriteString("  not loaded\n")
+	}
+
+	buf.WriteString("\nStack:\n")
+	buf.Write(stack)
+
+	err := os.WriteFile(fileName, buf.Bytes(), 0o664)
+	if err != nil {
+		return "", fmt.Errorf("write file (filename: %q): %w", fileName, err)
+	}
+	return fileName, nil
+}
+
+// showCrashMessage tells the player about the crash. Nothing is shown if the
+// screen or fonts were not initialized or are broken.
+func showCrashMessage() {
+	if screen.screen == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Error on show crash message: %v", r)
+		}
+	}()
+
+	font := NewFont("laudcn2.ttf", 16)
+	ShowMessageWindow(NewArea(), "redpattern.bmp", 400, 100, font, 255, 255, 255, msg("crashed"))
+}
+
+// RestoreCrashSave offers to continue the game saved on the last crash. The
+// crash save is removed whatever the answer is, before the game is run, so a
+// new crash of the game leaves a new one.
+func RestoreCrashSave(parentArea *Area) {
+	if !HasCrashSave() {
+		return
+	}
+
+	font := NewFont("laudcn2.ttf", 16)
+	if !AskYesNo(parentArea, font, msg("restoreCrash")) {
+		RemoveCrashSave()
+		return
+	}
+
+	g, info, err := loadSavedGame(GetCrashSaveFileName())
+	RemoveCrashSave()
+	if err != nil {
+		log.Printf("Error on restore crashed game: %v", err)
+		ShowMessageWindow(parentArea, "redpattern.bmp", 300, 80, font, 255, 255, 255, msg("corruptedSave"))
+		return
+	}
+	g.hinted = info.Hinted
+	game = g
+	game.Run()
+	game.Close()
+}
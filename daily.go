@@ -0,0 +1,240 @@
+package goeinstein
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+//nolint:golint,nosnakecase,stylecheck
+const (
+	// DAILY_VERSION is mixed into the seeds of daily puzzles. It must be
+	// increased when the generator gives other puzzles for the same seed, so
+	// players of different versions do not compete on different puzzles.
+	DAILY_VERSION    = 1
+	DAILY_DIFFICULTY = DIFFICULTY_NORMAL
+	DAY_FORMAT       = "2006-01-02"
+)
+
+// GetDailyDay returns the day of the daily puzzle at the local date of t.
+func GetDailyDay(t time.Time) string {
+	return t.Local().Format(DAY_FORMAT)
+}
+
+// GetDailySeed returns the seed of the daily puzzle: the version followed by
+// the digits of the date, e.g. 120261019.
+func GetDailySeed(day string) (int64, error) {
+	t, err := time.Parse(DAY_FORMAT, day)
+	if err != nil {
+		return 0, fmt.Errorf("parse day %q: %w", day, err)
+	}
+	date := int64(t.Year()*10000 + int(t.Month())*100 + t.Day())
+	return DAILY_VERSION*100000000 + date, nil
+}
+
+// GetDailyDayOfSeed returns the day of the daily puzzle with the seed. Seeds
+// of other games are Unix times, they never look like seeds of dailies.
+func GetDailyDayOfSeed(seed int64) (string, bool) {
+	if seed/100000000 != DAILY_VERSION {
+		return "", false
+	}
+	date := int(seed % 100000000)
+	t := time.Date(date/10000, time.Month(date/100%100), date%100, 0, 0, 0, 0, time.UTC)
+	day := t.Format(DAY_FORMAT)
+	if s, err := GetDailySeed(day); err != nil || s != seed {
+		return "", false
+	}
+	return day, true
+}
+
+// DailyResult is a solved daily puzzle.
+type DailyResult struct {
+	Time   int       `json:"time"` // seconds
+	Date   time.Time `json:"date"`
+	Hinted bool      `json:"hinted,omitempty"`
+}
+
+// DailyLog keeps the solved daily puzzles of the profile by day.
+type DailyLog struct {
+	Version int                     `json:"version"`
+	Days    map[string]*DailyResult `json:"days"`
+}
+
+func GetDailyLogFileName() string {
+	return filepath.Join(GetProfileDataDir(), "daily.json")
+}
+
+// LoadDailyLog reads the log. Errors are logged and an empty log is returned.
+func LoadDailyLog() *DailyLog {
+	l := &DailyLog{}
+	bs, err := os.ReadFile(GetDailyLogFileName())
+	if err == nil {
+		err = json.Unmarshal(bs, l)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("Error on read daily log (filename: %q): %v", GetDailyLogFileName(), err)
+		l = &DailyLog{}
+	}
+	if l.Days == nil {
+		l.Days = make(map[string]*DailyResult)
+	}
+	return l
+}
+
+func (l *DailyLog) Save() error {
+	l.Version = DAILY_VERSION
+	bs, err := json.MarshalIndent(l, "", "\t")
+	if err != nil {
+		return fmt.Errorf("marshal daily log: %w", err)
+	}
+	return WriteFileAtomic(GetDailyLogFileName(), bs, 0o664)
+}
+
+func (l *DailyLog) Get(day string) *DailyResult { return l.Days[day] }
+
+// RecordDaily marks the daily puzzle of the game as solved. The first result
+// of a day is kept.
+func RecordDaily(g *Game) {
+	l := LoadDailyLog()
+	if l.Get(g.daily) != nil {
+		return
+	}
+	l.Days[g.daily] = &DailyResult{
+		Time:   g.watch.GetElapsed() / 1000,
+		Date:   time.Now(),
+		Hinted: g.IsHinted(),
+	}
+	if err := l.Save(); err != nil {
+		log.Printf("Error on save daily log: %v", err)
+	}
+}
+
+// StartDaily generates the daily puzzle of the day and runs it.
+func StartDaily(area *Area, day string) {
+	seed, err := GetDailySeed(day)
+	if err == nil {
+		var g *Game
+		g, err = NewGameSeed(seed, NewGenConfigDifficulty(DAILY_DIFFICULTY))
+		if err == nil {
+			g.daily = day
+			game = g
+			game.Run()
+		}
+	}
+	if err != nil {
+		log.Printf("Error on daily puzzle: %v", err)
+		area.Draw()
+		font := NewFont("laudcn2.ttf", 16)
+		ShowMessageWindow(area, "redpattern.bmp", 500, 80, font, 255, 255, 255, msg("genError"))
+	}
+	area.UpdateMouse()
+	area.Draw()
+}
+
+// ShowDailyWindow shows the calendar of daily puzzles of a month. A day is
+// selected by a click, its puzzle can be played or its leaderboard shown.
+func ShowDailyWindow(parentArea *Area) {
+	titleFont := NewFont("nova.ttf", 26)
+	font := NewFont("laudcn2.ttf", 14)
+	timeFont := NewFont("laudcn2.ttf", 11)
+
+	today := GetDailyDay(time.Now())
+	selected := today
+	t, _ := time.Parse(DAY_FORMAT, today)
+	month := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	for {
+		dailyLog := LoadDailyLog()
+		area := NewArea()
+		area.AddManaged(parentArea, false)
+
+		var closed bool
+		var play, board bool
+		rebuild := func(fn func()) Command {
+			return FnCommand(func() {
+				fn()
+				area.FinishEventLoop()
+			})
+		}
+
+		area.Add(NewWindow(150, 60, 500, 480, "blue.bmp"))
+		area.Add(NewLabelAligh(titleFont, 150, 65, 500, 40, ALIGN_CENTER, ALIGN_MIDDLE, 255, 255, 0, msg("dailyPuzzle")))
+
+		prevCmd := rebuild(func() { month = month.AddDate(0, -1, 0) })
+		nextCmd := rebuild(func() { month = month.AddDate(0, 1, 0) })
+		area.Add(NewButtonText(170, 110, 40, 25, font, 255, 255, 0, "blue.bmp", "<", prevCmd))
+		area.Add(NewLabelAligh(font, 215, 110, 370, 25, ALIGN_CENTER, ALIGN_MIDDLE, 255, 255, 255, month.Format("2006-01")))
+		area.Add(NewButtonText(590, 110, 40, 25, font, 255, 255, 0, "blue.bmp", ">", nextCmd))
+		area.Add(NewKeyAccel(sdl.K_PAGEUP, prevCmd))
+		area.Add(NewKeyAccel(sdl.K_PAGEDOWN, nextCmd))
+
+		const cellW, cellH = 65, 45
+		// the week starts on Monday
+		col := (int(month.Weekday()) + 6) % 7
+		row := 0
+		for d := month; d.Month() == month.Month(); d = d.AddDate(0, 0, 1) {
+			day := d.Format(DAY_FORMAT)
+			x, y := int32(172+col*cellW), int32(145+row*cellH)
+			text := ToString(d.Day())
+			if day == selected {
+				text = "[" + text + "]"
+			}
+			res := dailyLog.Get(day)
+			if day > today {
+				area.Add(NewLabelAligh(font, x, y, cellW-5, 24, ALIGN_CENTER, ALIGN_MIDDLE, 128, 128, 128, text))
+			} else {
+				var r, b uint8 = 255, 255
+				if res != nil {
+					r, b = 0, 0
+				} else if day == today {
+					b = 0
+				}
+				area.Add(NewButtonText(x, y, cellW-5, 24, font, r, 255, b, "blue.bmp", text, rebuild(func() { selected = day })))
+			}
+			if res != nil {
+				area.Add(NewLabelAligh(timeFont, x, y+24, cellW-5, 16, ALIGN_CENTER, ALIGN_MIDDLE, 255, 255, 255, SecToStr(uint64(res.Time))))
+			}
+			col++
+			if col == 7 {
+				col = 0
+				row++
+			}
+		}
+
+		status := msg("dailyNotSolved")
+		if res := dailyLog.Get(selected); res != nil {
+			status = fmt.Sprintf("%s %s", msg("dailySolved"), SecToStr(uint64(res.Time)))
+		}
+		area.Add(NewLabelAligh(font, 170, 420, 460, 25, ALIGN_CENTER, ALIGN_MIDDLE, 255, 255, 255, selected+": "+status))
+
+		if dailyLog.Get(selected) == nil {
+			area.Add(NewButtonText(170, 500, 140, 25, font, 255, 255, 0, "blue.bmp", msg("dailyPlay"), rebuild(func() { play = true })))
+		}
+		area.Add(NewButtonText(330, 500, 140, 25, font, 255, 255, 0, "blue.bmp", msg("dailyBoard"), rebuild(func() { board = true })))
+		exitCmd := rebuild(func() { closed = true })
+		area.Add(NewButtonText(530, 500, 100, 25, font, 255, 255, 0, "blue.bmp", msg("close"), exitCmd))
+		area.Add(NewKeyAccel(sdl.K_ESCAPE, exitCmd))
+
+		area.Run()
+		area.Close()
+
+		if closed || IsQuitRequested() {
+			return
+		}
+		switch {
+		case play:
+			StartDaily(parentArea, selected)
+		case board:
+			scores := NewHallOfFame()
+			ShowScoresWindowHighlight(parentArea, scores, scores.GetDailyBoard(selected), -1)
+		}
+		if IsQuitRequested() {
+			return
+		}
+	}
+}
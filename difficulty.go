@@ -0,0 +1,176 @@
+package goeinstein
+
+import (
+	"math"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+//nolint:golint,nosnakecase,stylecheck
+const (
+	DIFFICULTY_EASY   = "easy"
+	DIFFICULTY_NORMAL = "normal"
+	DIFFICULTY_HARD   = "hard"
+	DIFFICULTY_CUSTOM = "custom"
+
+	MAX_RULE_WEIGHT = 10
+	MAX_REDUNDANCY  = 5
+)
+
+//nolint:golint,nosnakecase,stylecheck
+var DIFFICULTIES = []string{DIFFICULTY_EASY, DIFFICULTY_NORMAL, DIFFICULTY_HARD}
+
+// NewGenConfigDifficulty returns generator settings of the difficulty preset.
+// Settings of the custom difficulty are read from the storage. Only the easy
+// and the hard presets filter rules by quality, the normal one generates
+// puzzles as the original game.
+func NewGenConfigDifficulty(name string) *GenConfig {
+	cfg := NewGenConfig()
+
+	switch name {
+	case DIFFICULTY_EASY:
+		cfg.Weights = RuleWeights{
+			"near":      4,
+			"open":      2,
+			"under":     3,
+			"direction": 3,
+			"between":   2,
+		}
+		cfg.Quality = NewQualityConfig()
+		cfg.Quality.MaxPerType["open"] = 4
+		cfg.Redundancy = 3
+	case DIFFICULTY_HARD:
+		cfg.Quality = NewQualityConfig()
+		cfg.Weights["open"] = 0
+		cfg.Weights["between"] = 4
+		cfg.Minimal = true
+	case DIFFICULTY_CUSTOM:
+		storage := GetStorage()
+		for _, t := range GetRuleTypes() {
+			cfg.Weights[t.Tag] = storage.GetInt("custom_weight_"+t.Tag, cfg.Weights[t.Tag])
+		}
+		cfg.Redundancy = storage.GetInt("custom_redundancy", cfg.Redundancy)
+		cfg.Minimal = storage.GetInt("custom_minimal", boolToInt[cfg.Minimal]) > 0
+	}
+	return cfg
+}
+
+func SaveCustomGenConfig(cfg *GenConfig) {
+	storage := GetStorage()
+	for _, t := range GetRuleTypes() {
+		storage.SetInt("custom_weight_"+t.Tag, cfg.Weights[t.Tag])
+	}
+	storage.SetInt("custom_redundancy", cfg.Redundancy)
+	storage.SetInt("custom_minimal", boolToInt[cfg.Minimal])
+}
+
+func GetDifficulty() string {
+	return GetStorage().GetString("difficulty", DIFFICULTY_NORMAL)
+}
+
+func SetDifficulty(name string) {
+	GetStorage().SetString("difficulty", name)
+}
+
+// GetDifficultyName returns the difficulty preset which cfg is equal to, or
+// DIFFICULTY_CUSTOM.
+func GetDifficultyName(cfg *GenConfig) string {
+	for _, name := range DIFFICULTIES {
+		p := NewGenConfigDifficulty(name)
+		if p.Redundancy != cfg.Redundancy || p.Minimal != cfg.Minimal || (p.Quality == nil) != (cfg.Quality == nil) {
+			continue
+		}
+		same := true
+		for _, t := range GetRuleTypes() {
+			if p.Weights[t.Tag] != cfg.Weights[t.Tag] {
+				same = false
+				break
+			}
+		}
+		if same {
+			return name
+		}
+	}
+	return DIFFICULTY_CUSTOM
+}
+
+// ShowCustomGameWindow lets the player tune the puzzle generator. It returns
+// nil if the dialog was cancelled.
+func ShowCustomGameWindow(parentArea *Area) *GenConfig {
+	titleFont := NewFont("nova.ttf", 26)
+	font := NewFont("laudcn2.ttf", 14)
+
+	cfg := NewGenConfigDifficulty(GetDifficulty())
+	types := GetRuleTypes()
+	weights := make([]float32, len(types))
+	var redundancy float32
+	var minimal bool
+	setValues := func(c *GenConfig) {
+		for i, t := range types {
+			weights[i] = float32(c.Weights[t.Tag]) / MAX_RULE_WEIGHT
+		}
+		redundancy = float32(c.Redundancy) / MAX_REDUNDANCY
+		minimal = c.Minimal
+	}
+	setValues(cfg)
+
+	area := NewArea()
+	area.Add(parentArea)
+	area.Add(NewWindow(200, 110, 400, 380, "blue.bmp"))
+	area.Add(NewLabelAligh(titleFont, 200, 115, 400, 40, ALIGN_CENTER, ALIGN_MIDDLE, 255, 255, 0, msg("customGame")))
+
+	x := int32(230)
+	for _, name := range DIFFICULTIES {
+		name := name
+		presetCmd := FnCommand(func() {
+			// the preset also sets what has no control, e.g. the quality
+			cfg = NewGenConfigDifficulty(name)
+			setValues(cfg)
+			area.Draw()
+		})
+		area.Add(NewButtonText(x, 165, 105, 25, font, 255, 255, 0, "blue.bmp", msg(name), presetCmd))
+		x += 115
+	}
+
+	LABEL := func(y int32, s string) {
+		area.Add(NewLabelAligh(font, 230, y, 150, 20, ALIGN_LEFT, ALIGN_MIDDLE, 255, 255, 255, msg(s)))
+	}
+
+	y := int32(210)
+	for i, t := range types {
+		LABEL(y, t.Title)
+		area.Add(NewSlider(390, y+2, 180, 16, &weights[i]))
+		y += 25
+	}
+	LABEL(y, "redundancy")
+	area.Add(NewSlider(390, y+2, 180, 16, &redundancy))
+	y += 30
+	area.Add(NewCheckbox(230, y, 20, 20, font, 255, 255, 255, "blue.bmp", &minimal))
+	area.Add(NewLabelAligh(font, 265, y, 300, 20, ALIGN_LEFT, ALIGN_MIDDLE, 255, 255, 255, msg("minimalRules")))
+
+	var ok bool
+	okCmd := NewOkCommand(area, &ok)
+	exitCmd := NewExitCommand(area)
+	area.Add(NewButtonText(305, 450, 90, 25, font, 255, 255, 0, "blue.bmp", msg("start"), okCmd))
+	area.Add(NewButtonText(405, 450, 90, 25, font, 255, 255, 0, "blue.bmp", msg("cancel"), exitCmd))
+	area.Add(NewKeyAccel(sdl.K_ESCAPE, exitCmd))
+	area.Add(NewKeyAccel(sdl.K_RETURN, okCmd))
+	area.Run()
+
+	if !ok {
+		return nil
+	}
+
+	for i, t := range types {
+		cfg.Weights[t.Tag] = int(math.Round(float64(weights[i] * MAX_RULE_WEIGHT)))
+	}
+	cfg.Redundancy = int(math.Round(float64(redundancy * MAX_REDUNDANCY)))
+	cfg.Minimal = minimal
+
+	name := GetDifficultyName(cfg)
+	if name == DIFFICULTY_CUSTOM {
+		SaveCustomGenConfig(cfg)
+	}
+	SetDifficulty(name)
+	return NewGenConfigDifficulty(name)
+}
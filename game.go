@@ -1,7 +1,9 @@
 package goeinstein
 
 import (
+	"fmt"
 	"io"
+	"log"
 	"math/rand"
 	"time"
 
@@ -34,18 +36,21 @@ func (g *GameBackground) Draw() {
 type ToggleHintCommand struct {
 	verHints *VertHints
 	horHints *HorHints
+	recorder MoveRecorder
 }
 
 var _ Command = (*ToggleHintCommand)(nil)
 
-func NewToggleHintCommand(v *VertHints, h *HorHints) *ToggleHintCommand {
+func NewToggleHintCommand(v *VertHints, h *HorHints, r MoveRecorder) *ToggleHintCommand {
 	t := &ToggleHintCommand{}
 	t.verHints = v
 	t.horHints = h
+	t.recorder = r
 	return t
 }
 
 func (t *ToggleHintCommand) DoAction() {
+	t.recorder.RecordMove(Move{Kind: MOVE_SWITCH})
 	t.verHints.ToggleExcluded()
 	t.horHints.ToggleExcluded()
 }
@@ -74,13 +79,17 @@ func NewWatch() *Watch {
 	return w
 }
 
-func NewWatchStream(stream io.Reader) *Watch {
+func NewWatchStream(stream io.Reader) (*Watch, error) {
+	elapsed, err := ReadInt(stream)
+	if err != nil {
+		return nil, err
+	}
 	w := &Watch{}
-	w.elapsed = uint64(ReadInt(stream))
+	w.elapsed = uint64(elapsed)
 	w.lastUpdate = 0
 	w.Stop()
 	w.font = NewFont("luximb.ttf", 16)
-	return w
+	return w, nil
 }
 
 func (w *Watch) Close() {
@@ -140,19 +149,22 @@ type PauseGameCommand struct {
 	gameArea   *Area
 	watch      *Watch
 	background AreaWidgeter
+	recorder   MoveRecorder
 }
 
 var _ Command = (*PauseGameCommand)(nil)
 
-func NewPauseGameCommand(a *Area, w *Watch, bg AreaWidgeter) *PauseGameCommand {
+func NewPauseGameCommand(a *Area, w *Watch, bg AreaWidgeter, r MoveRecorder) *PauseGameCommand {
 	p := &PauseGameCommand{}
 	p.gameArea = a
 	p.watch = w
 	p.background = bg
+	p.recorder = r
 	return p
 }
 
 func (p *PauseGameCommand) DoAction() {
+	p.recorder.RecordMove(Move{Kind: MOVE_PAUSE})
 	p.watch.Stop()
 	area := NewArea()
 	area.AddManaged(p.background, false)
@@ -184,22 +196,53 @@ func NewWinCommand(a *Area, w *Watch, g *Game) *WinCommand {
 }
 
 func (w *WinCommand) DoAction() {
+	w.game.finished = true
 	sound.Play("applause.wav")
 	w.watch.Stop()
+	// the F8 accelerator wins without solving the puzzle, such wins are kept
+	// out of the history, the daily log and the hall of fame
+	solved := w.game.possibilities.IsSolved() && w.game.possibilities.IsValid(&w.game.solvedPuzzle)
+	if solved {
+		RecordGame(w.game, GAME_WON)
+		if w.game.daily != "" {
+			RecordDaily(w.game)
+		}
+	}
 	font := NewFont("laudcn2.ttf", 20)
 	ShowMessageWindow(w.gameArea, "marble1.bmp", 500, 70, font, 255, 0, 0, msg("won"))
 	w.gameArea.Draw()
-	scores := NewTopScores()
+	if !solved {
+		w.gameArea.FinishEventLoop()
+		return
+	}
+	scores := NewHallOfFame()
 	defer scores.Close()
-	score := w.watch.GetElapsed() / 1000
+	entry := &TopScoreEntry{
+		Time:       w.watch.GetElapsed() / 1000,
+		Date:       time.Now(),
+		Seed:       w.game.GetSeed(),
+		Difficulty: w.game.GetDifficulty(),
+		Size:       PUZZLE_SIZE,
+		Hinted:     w.game.IsHinted(),
+		Profile:    GetProfiles().Current,
+		Day:        w.game.daily,
+		Moves:      w.game.moveLog.Encode(),
+	}
+	qualified := scores.IsQualified(entry)
+	if qualified {
+		entry.Name = EnterNameDialog(w.gameArea)
+	} else {
+		entry.Name = GetStorage().GetString("lastName", GetProfiles().GetCurrent().GetName())
+	}
+	if err := entry.Sign(); err != nil {
+		log.Printf("Error on sign score: %v", err)
+	}
 	pos := -1
-	if !w.game.IsHinted() {
-		if !scores.IsFull() || (score < scores.GetMaxScore()) {
-			name := EnterNameDialog(w.gameArea)
-			pos = scores.Add(name, score)
-		}
+	if qualified {
+		pos = scores.Add(entry)
 	}
-	ShowScoresWindowHighlight(w.gameArea, scores, pos)
+	scores.Submit(entry)
+	ShowScoresWindowHighlight(w.gameArea, scores, scores.getBoard(entry.Difficulty, entry.Size, entry.Day), pos)
 	w.gameArea.FinishEventLoop()
 }
 
@@ -238,6 +281,7 @@ func NewFailCommand(a *Area, g *Game) *FailCommand {
 
 func (f *FailCommand) DoAction() {
 	sound.Play("glasbk2.wav")
+	f.game.mistakes++
 	var restart bool
 	var newGame bool
 	font := NewFont("laudcn2.ttf", 24)
@@ -253,15 +297,26 @@ func (f *FailCommand) DoAction() {
 	exitCmd := NewExitCommand(area)
 	area.Add(NewButtonText(450, 340, 90, 25, btnFont, 255, 255, 0, "redpattern.bmp", msg("exit"), exitCmd))
 	area.Run()
+	if newGame {
+		RecordGame(f.game, GAME_FAILED)
+		if err := f.game.NewGame(); err != nil {
+			log.Printf("Error on new game: %v", err)
+			msgFont := NewFont("laudcn2.ttf", 16)
+			ShowMessageWindow(f.gameArea, "redpattern.bmp", 500, 80, msgFont, 255, 255, 255, msg("genError"))
+			f.game.finished = true
+			f.gameArea.FinishEventLoop()
+			return
+		}
+	}
 	if restart || newGame {
-		if newGame {
-			f.game.NewGame()
-		} else {
+		if restart {
 			f.game.Restart()
 		}
 		f.gameArea.Draw()
 		f.gameArea.UpdateMouse()
 	} else {
+		f.game.finished = true
+		RecordGame(f.game, GAME_FAILED)
 		f.gameArea.FinishEventLoop()
 	}
 }
@@ -440,7 +495,24 @@ type Game struct {
 	hinted            bool
 	savedSolvedPuzzle SolvedPuzzle
 	savedRules        Rules
-}
+	config            *GenConfig
+	difficulty        string
+	seed              int64
+	started           time.Time
+	finished          bool
+	lastAutosave      uint64
+	mistakes          int
+	restarts          int
+	// daily is the day of the daily puzzle, "" for other games
+	daily   string
+	moveLog *MoveLog
+	history *UndoHistory
+}
+
+var (
+	_ TimerHandler = (*Game)(nil)
+	_ MoveRecorder = (*Game)(nil)
+)
 
 func (g *Game) GetSolvedPuzzle() SolvedPuzzle    { return g.solvedPuzzle }
 func (g *Game) GetRules() Rules                  { return g.rules }
@@ -449,17 +521,47 @@ func (g *Game) GetVerHints() *VertHints          { return g.verHints }
 func (g *Game) GetHorHints() *HorHints           { return g.horHints }
 func (g *Game) IsHinted() bool                   { return g.hinted }
 func (g *Game) SetHinted()                       { g.hinted = true }
+func (g *Game) GetDifficulty() string            { return g.difficulty }
+
+// GetSeed returns the seed the puzzle was generated with, or 0 if it is unknown.
+func (g *Game) GetSeed() int64 { return g.seed }
 
 func NewGame() *Game {
-	rand := rand.New(rand.NewSource(time.Now().Unix()))
-	return NewGameRand(rand)
+	g, err := NewGameSeed(time.Now().Unix(), NewGenConfigDifficulty(GetDifficulty()))
+	if err != nil {
+		panic(fmt.Errorf("new game: %w", err))
+	}
+	return g
+}
+
+// NewGameSeed generates a game with cfg and remembers the seed.
+func NewGameSeed(seed int64, cfg *GenConfig) (*Game, error) {
+	g, err := NewGameConfig(rand.New(rand.NewSource(seed)), cfg)
+	if err != nil {
+		return nil, err
+	}
+	g.seed = seed
+	return g, nil
 }
 
 func NewGameRand(rand *rand.Rand) *Game {
+	g, err := NewGameConfig(rand, NewGenConfigDifficulty(GetDifficulty()))
+	if err != nil {
+		panic(fmt.Errorf("new game: %w", err))
+	}
+	return g
+}
+
+func NewGameConfig(rand *rand.Rand, cfg *GenConfig) (*Game, error) {
 	g := &Game{
-		iconSet: NewIconSet(),
+		iconSet:    NewIconSet(),
+		config:     cfg,
+		difficulty: GetDifficultyName(cfg),
+	}
+	err := g.GenPuzzle(rand)
+	if err != nil {
+		return nil, err
 	}
-	g.GenPuzzle(rand)
 
 	g.verHints = NewVertHints(g.iconSet, &g.rules)
 	g.horHints = NewHorHints(g.iconSet, &g.rules)
@@ -467,32 +569,68 @@ func NewGameRand(rand *rand.Rand) *Game {
 
 	g.possibilities = NewPossibilities()
 	OpenInitial(g.possibilities, &g.rules, excluder)
+	g.moveLog = NewMoveLog(options.AutoHints.Value())
+	g.history = NewUndoHistory()
 
 	hinter := NewRuleHinter(&g.rules, excluder)
 	g.puzzle = NewPuzzle(g.iconSet, &g.solvedPuzzle, g.possibilities, hinter)
+	g.SetRecorders()
 	g.watch = NewWatch()
-	return g
+	return g, nil
 }
 
-func NewGameStream(stream io.Reader) *Game {
+func NewGameStream(stream io.Reader) (*Game, error) {
 	g := &Game{
-		iconSet: NewIconSet(),
+		config: NewGenConfigDifficulty(GetDifficulty()),
 	}
 	g.PleaseWait()
 
-	LoadPuzzle(&g.solvedPuzzle, stream)
-	LoadRules(&g.rules, stream)
+	err := LoadPuzzle(&g.solvedPuzzle, stream)
+	if err != nil {
+		return nil, fmt.Errorf("load puzzle: %w", err)
+	}
+	err = LoadRules(&g.rules, stream)
+	if err != nil {
+		return nil, fmt.Errorf("load rules: %w", err)
+	}
 	g.savedSolvedPuzzle = g.solvedPuzzle
 	g.savedRules = g.rules[:]
-	g.possibilities = NewPossibilitiesStream(stream)
-	g.verHints = NewVertHintsStream(g.iconSet, &g.rules, stream)
-	g.horHints = NewHorHintsStream(g.iconSet, &g.rules, stream)
+	g.possibilities, err = NewPossibilitiesStream(stream)
+	if err != nil {
+		return nil, fmt.Errorf("load possibilities: %w", err)
+	}
+	g.iconSet = NewIconSet()
+	g.verHints, err = NewVertHintsStream(g.iconSet, &g.rules, stream)
+	if err != nil {
+		g.iconSet.Close()
+		return nil, fmt.Errorf("load vertical hints: %w", err)
+	}
+	g.horHints, err = NewHorHintsStream(g.iconSet, &g.rules, stream)
+	if err != nil {
+		g.iconSet.Close()
+		return nil, fmt.Errorf("load horizontal hints: %w", err)
+	}
+	g.watch, err = NewWatchStream(stream)
+	if err != nil {
+		g.iconSet.Close()
+		return nil, fmt.Errorf("load watch: %w", err)
+	}
+	g.moveLog, err = NewMoveLogStream(stream)
+	if err != nil {
+		g.iconSet.Close()
+		return nil, fmt.Errorf("load moves: %w", err)
+	}
+	g.history, err = NewUndoHistoryStream(stream, len(g.verHints.GetExcluded()), len(g.horHints.GetExcluded()))
+	if err != nil {
+		g.iconSet.Close()
+		return nil, fmt.Errorf("load undo history: %w", err)
+	}
 	excluder := NewHintsExcluder(g.verHints, g.horHints)
 	hinter := NewRuleHinter(&g.rules, excluder)
 	g.puzzle = NewPuzzle(g.iconSet, &g.solvedPuzzle, g.possibilities, hinter)
-	g.watch = NewWatchStream(stream)
+	g.SetRecorders()
 	g.hinted = true
-	return g
+	return g, nil
 }
 
 func (g *Game) Close() {
@@ -511,6 +649,65 @@ func (g *Game) Save(stream io.Writer) {
 	g.verHints.Save(stream)
 	g.horHints.Save(stream)
 	g.watch.Save(stream)
+	g.moveLog.Save(stream)
+	g.history.Save(stream)
+}
+
+// SetRecorders makes the game record the moves of the player on the puzzle
+// and the hints lists.
+func (g *Game) SetRecorders() {
+	g.puzzle.SetRecorder(g)
+	g.verHints.SetRecorder(g)
+	g.horHints.SetRecorder(g)
+}
+
+// RecordMove adds the move of the player to the move log. The state before
+// the moves which can be undone is remembered.
+func (g *Game) RecordMove(m Move) {
+	m.ElapsedMs = g.watch.GetElapsed()
+	if m.IsCardMove() {
+		g.history.Push(&UndoStep{State: g.snapshot(), Move: &m})
+	} else if m.IsUndoable() {
+		g.history.Push(&UndoStep{State: g.snapshot()})
+	}
+	g.moveLog.Add(m)
+}
+
+func (g *Game) snapshot() Snapshot {
+	return Snapshot{
+		Possib:      *g.possibilities,
+		VerExcluded: g.verHints.GetExcluded(),
+		HorExcluded: g.horHints.GetExcluded(),
+	}
+}
+
+func (g *Game) restore(s Snapshot) {
+	*g.possibilities = s.Possib
+	g.verHints.SetExcluded(s.VerExcluded)
+	g.horHints.SetExcluded(s.HorExcluded)
+	g.puzzle.Draw()
+}
+
+// Undo returns to the state before the last move or hints change.
+func (g *Game) Undo() bool {
+	s := g.history.Undo(g.snapshot())
+	if s == nil {
+		return false
+	}
+	g.RecordMove(Move{Kind: MOVE_UNDO})
+	g.restore(s.State)
+	return true
+}
+
+// Redo makes the last undone move or hints change again.
+func (g *Game) Redo() bool {
+	s := g.history.Redo(g.snapshot())
+	if s == nil {
+		return false
+	}
+	g.RecordMove(Move{Kind: MOVE_REDO})
+	g.restore(s.State)
+	return true
 }
 
 func (g *Game) DeleteRules() {
@@ -528,25 +725,20 @@ func (g *Game) PleaseWait() {
 	screen.Flush()
 }
 
-func (g *Game) GenPuzzle(rand *rand.Rand) {
+func (g *Game) GenPuzzle(rand *rand.Rand) error {
 	g.PleaseWait()
 
-	var horRules, verRules int
-	for {
-		if len(g.rules) > 0 {
-			g.DeleteRules()
-		}
-		GenPuzzle(&g.solvedPuzzle, &g.rules, rand)
-		GetHintsQty(&g.rules, &verRules, &horRules)
-		if horRules <= 24 && verRules <= 15 {
-			break
-		}
+	err := GenGamePuzzle(&g.solvedPuzzle, &g.rules, rand, g.config)
+	if err != nil {
+		return err
 	}
 
 	g.savedSolvedPuzzle = g.solvedPuzzle
 	g.savedRules = g.rules[:]
+	g.started = time.Now()
 
-	g.hinted = options.AutoHints.value
+	g.hinted = options.AutoHints.Value()
+	return nil
 }
 
 func (g *Game) ResetVisuals() {
@@ -555,17 +747,32 @@ func (g *Game) ResetVisuals() {
 	g.verHints.Reset(&g.rules)
 	g.horHints.Reset(&g.rules)
 	OpenInitial(g.possibilities, &g.rules, NewHintsExcluder(g.verHints, g.horHints))
+	g.moveLog = NewMoveLog(options.AutoHints.Value())
+	g.history = NewUndoHistory()
 	g.watch.Reset()
 }
 
-func (g *Game) NewGame() {
-	rand := rand.New(rand.NewSource(time.Now().Unix()))
-	g.NewGameRand(rand)
+func (g *Game) NewGame() error {
+	seed := time.Now().Unix()
+	err := g.NewGameRand(rand.New(rand.NewSource(seed)))
+	if err != nil {
+		return err
+	}
+	g.seed = seed
+	return nil
 }
 
-func (g *Game) NewGameRand(rand *rand.Rand) {
-	g.GenPuzzle(rand)
+func (g *Game) NewGameRand(rand *rand.Rand) error {
+	err := g.GenPuzzle(rand)
+	if err != nil {
+		return fmt.Errorf("new game: %w", err)
+	}
+	g.seed = 0
+	g.mistakes = 0
+	g.restarts = 0
+	g.daily = ""
 	g.ResetVisuals()
+	return nil
 }
 
 func (g *Game) Restart() {
@@ -574,13 +781,14 @@ func (g *Game) Restart() {
 
 	g.ResetVisuals()
 	g.hinted = true
+	g.restarts++
 }
 
 func (g *Game) Run() {
 	area := NewArea()
 	btnFont := NewFont("laudcn2.ttf", 14)
 
-	area.SetTimer(300, g.watch)
+	area.SetTimer(300, g)
 
 	background := NewGameBackground()
 	area.Add(background)
@@ -594,25 +802,40 @@ func (g *Game) Run() {
 	area.AddManaged(g.verHints, false)
 	area.AddManaged(g.horHints, false)
 
+	// four columns of buttons fit between the left edge and the horizontal
+	// hints which start at HORHINTS_TILE_X
 	BUTTON := func(x, y int32, text string, cmd Command) {
-		area.Add(NewButtonTextBevel(x, y, 94, 30, btnFont, 255, 255, 0, "btn.bmp", msg(text), false, cmd))
+		area.Add(NewButtonTextBevel(x, y, 75, 30, btnFont, 255, 255, 0, "btn.bmp", msg(text), false, cmd))
 	}
 
-	pauseGameCmd := NewPauseGameCommand(area, g.watch, background)
+	pauseGameCmd := NewPauseGameCommand(area, g.watch, background, g)
 	BUTTON(12, 400, "pause", pauseGameCmd)
-	toggleHintsCmd := NewToggleHintCommand(g.verHints, g.horHints)
-	BUTTON(119, 400, "switch", toggleHintsCmd)
+	toggleHintsCmd := NewToggleHintCommand(g.verHints, g.horHints, g)
+	BUTTON(94, 400, "switch", toggleHintsCmd)
 	saveCmd := NewSaveGameCommand(area, g.watch, background, g)
 	BUTTON(12, 440, "save", saveCmd)
 	optionsCmd := NewGameOptionsCommand(area)
-	BUTTON(119, 440, "options", optionsCmd)
+	BUTTON(94, 440, "options", optionsCmd)
+	undoCmd := NewUndoCommand(g)
+	BUTTON(176, 400, "undo", undoCmd)
+	area.Add(NewKeyAccelMod(sdl.K_z, sdl.KMOD_CTRL, undoCmd))
+	redoCmd := NewRedoCommand(g)
+	BUTTON(176, 440, "redo", redoCmd)
+	area.Add(NewKeyAccelMod(sdl.K_y, sdl.KMOD_CTRL, redoCmd))
 	exitGameCmd := NewExitCommand(area)
-	BUTTON(226, 400, "exit", exitGameCmd)
+	BUTTON(258, 400, "exit", exitGameCmd)
 	area.Add(NewKeyAccel(sdl.K_ESCAPE, exitGameCmd))
 	helpCmd := NewHelpCommand(area, g.watch, background)
-	BUTTON(226, 440, "help", helpCmd)
+	BUTTON(258, 440, "help", helpCmd)
 	area.AddManaged(g.watch, false)
 
 	g.watch.Start()
+	g.lastAutosave = sdl.GetTicks64()
+	runningGame = g
 	area.Run()
+	runningGame = nil
+	if !g.finished {
+		RecordGame(g, GAME_ABANDONED)
+	}
+	g.SaveOnExit()
 }
@@ -1,6 +1,7 @@
 package goeinstein
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/veandco/go-sdl2/sdl"
@@ -27,6 +28,7 @@ type HorHints struct {
 	numbersArr    []int
 	showExcluded  bool
 	highlighted   int
+	recorder      MoveRecorder
 }
 
 func NewHorHints(is *IconSet, r *Rules) *HorHints {
@@ -36,18 +38,30 @@ func NewHorHints(is *IconSet, r *Rules) *HorHints {
 	return h
 }
 
-func NewHorHintsStream(is *IconSet, rl *Rules, stream io.Reader) *HorHints {
+func NewHorHintsStream(is *IconSet, rl *Rules, stream io.Reader) (*HorHints, error) {
 	h := &HorHints{}
 	h.iconSet = is
 
-	qty := ReadInt(stream)
+	qty, err := ReadInt(stream)
+	if err != nil {
+		return nil, err
+	}
 
 	for i := 0; i < qty; i++ {
-		no := ReadInt(stream)
+		no, err := ReadInt(stream)
+		if err != nil {
+			return nil, err
+		}
+		if no < 0 || no >= len(*rl) {
+			return nil, fmt.Errorf("wrong rule number: %d", no)
+		}
 		h.numbersArr = append(h.numbersArr, no)
 		r := GetRule(rl, no)
-		excluded := ReadInt(stream) > 0
-		if excluded {
+		excluded, err := ReadInt(stream)
+		if err != nil {
+			return nil, err
+		}
+		if excluded > 0 {
 			h.excludedRules = append(h.excludedRules, r)
 			h.rules = append(h.rules, nil)
 		} else {
@@ -56,11 +70,15 @@ func NewHorHintsStream(is *IconSet, rl *Rules, stream io.Reader) *HorHints {
 		}
 	}
 
-	h.showExcluded = ReadInt(stream) > 0
+	showExcluded, err := ReadInt(stream)
+	if err != nil {
+		return nil, err
+	}
+	h.showExcluded = showExcluded > 0
 
 	x, y, _ := sdl.GetMouseState()
 	h.highlighted = h.GetRuleNo(x, y)
-	return h
+	return h, nil
 }
 
 func (h *HorHints) Reset(r *Rules) {
@@ -106,7 +124,7 @@ func (h *HorHints) DrawCell(col, row int) {
 		}
 	}
 
-	if options.AutoHints.value {
+	if options.AutoHints.Value() {
 		if h.showExcluded {
 			if r == nil && no < len(h.rules) {
 				r = h.rules[no]
@@ -127,7 +145,7 @@ func (h *HorHints) DrawCell(col, row int) {
 	}
 
 	if r != nil {
-		r.Draw(x, y, h.iconSet, no == h.highlighted)
+		DrawRule(r, x, y, h.iconSet, no == h.highlighted)
 	} else {
 		for i := int32(0); i < 3; i++ {
 			screen.Draw(x+HORHINTS_TILE_HEIGHT*i, y, h.iconSet.GetEmptyHintIcon())
@@ -152,12 +170,14 @@ func (h *HorHints) OnMouseButtonDown(button uint8, x, y int32) bool {
 	if h.showExcluded {
 		r := h.excludedRules[no]
 		if r != nil {
+			h.recordHint(MOVE_HINT_RESTORE, no)
 			sound.Play("whizz.wav")
 			h.rules[no] = r
 			h.excludedRules[no] = nil
 			h.DrawCell(col, row)
 		}
-	} else {
+	} else if h.rules[no] != nil {
+		h.recordHint(MOVE_HINT_EXCLUDE, no)
 		h.Exclude(no)
 	}
 
@@ -189,6 +209,40 @@ func (h *HorHints) Exclude(no int) {
 	}
 }
 
+// SetRecorder sets the recorder of the hints excluded and restored by the
+// player, it may be nil.
+func (h *HorHints) SetRecorder(r MoveRecorder) { h.recorder = r }
+
+func (h *HorHints) recordHint(kind, no int) {
+	if h.recorder != nil {
+		h.recorder.RecordMove(Move{Kind: kind, Col: no, Row: int(SHOW_HORIZ)})
+	}
+}
+
+// GetExcluded returns which hints are excluded.
+func (h *HorHints) GetExcluded() []bool {
+	excluded := make([]bool, len(h.numbersArr))
+	for i := range excluded {
+		excluded[i] = h.rules[i] == nil
+	}
+	return excluded
+}
+
+// SetExcluded excludes and restores hints as returned by GetExcluded.
+func (h *HorHints) SetExcluded(excluded []bool) {
+	for i, e := range excluded {
+		if i >= len(h.rules) {
+			break
+		}
+		if e && h.rules[i] != nil {
+			h.excludedRules[i], h.rules[i] = h.rules[i], nil
+		} else if !e && h.excludedRules[i] != nil {
+			h.rules[i], h.excludedRules[i] = h.excludedRules[i], nil
+		}
+	}
+	h.Draw()
+}
+
 func (h *HorHints) ToggleExcluded() {
 	h.showExcluded = !h.showExcluded
 	h.Draw()
@@ -0,0 +1,81 @@
+package leaderboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//nolint:golint,nosnakecase,stylecheck
+const CLIENT_TIMEOUT = 5 * time.Second
+
+// Client is the HTTP/JSON client of a leaderboard server.
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+var _ Backend = (*Client)(nil)
+
+// NewClient returns the client of the server at baseURL, e.g.
+// "http://localhost:8080".
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: CLIENT_TIMEOUT},
+	}
+}
+
+func (c *Client) GetURL() string { return c.baseURL }
+
+func (c *Client) Submit(s *Submission) error {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("marshal submission: %w", err)
+	}
+	resp, err := c.http.Post(c.baseURL+"/scores", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp)
+}
+
+func (c *Client) Top(board string, n int) ([]*Score, error) {
+	q := url.Values{}
+	q.Set("board", board)
+	q.Set("n", strconv.Itoa(n))
+	resp, err := c.http.Get(c.baseURL + "/scores?" + q.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if err = checkResponse(resp); err != nil {
+		return nil, err
+	}
+	var scores []*Score
+	if err = json.NewDecoder(resp.Body).Decode(&scores); err != nil {
+		return nil, fmt.Errorf("%w: decode scores: %v", ErrUnavailable, err)
+	}
+	return scores, nil
+}
+
+// checkResponse returns ErrRejected on 4xx and ErrUnavailable on other
+// unsuccessful statuses with the text sent by the server.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	text, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	kind := ErrUnavailable
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		kind = ErrRejected
+	}
+	return fmt.Errorf("%w: %s: %s", kind, resp.Status, strings.TrimSpace(string(text)))
+}
@@ -0,0 +1,74 @@
+// Package leaderboard is the online hall of fame: the protocol, an HTTP/JSON
+// client, a reference server and an offline queue. It does not depend on the
+// game, so the server runs without SDL.
+//
+// Protocol:
+//
+//	POST /scores                  body: Submission, 204 on success
+//	GET  /scores?board=KEY&n=N    response: []*Score, best first
+//
+// Errors are returned with a 4xx status if the request is wrong and 5xx if
+// the server fails, the body is the text of the error.
+package leaderboard
+
+import (
+	"errors"
+	"time"
+)
+
+//nolint:golint,nosnakecase,stylecheck
+const (
+	MAX_NAME_LEN = 64
+	// MAX_TOP is the largest N of a fetch.
+	MAX_TOP = 100
+)
+
+var (
+	// ErrUnavailable is returned if the server cannot be reached or fails,
+	// the request can be repeated later.
+	ErrUnavailable = errors.New("leaderboard is unavailable")
+	// ErrRejected is returned if the server refused the request, repeating
+	// it does not help.
+	ErrRejected = errors.New("rejected by leaderboard")
+)
+
+// Score is an entry of a board. Seed, Difficulty, Size and Day are the code
+// of the puzzle: the puzzle is generated again from them.
+type Score struct {
+	Name       string    `json:"name"`
+	Time       int       `json:"time"` // seconds
+	Date       time.Time `json:"date"`
+	Seed       int64     `json:"seed"`
+	Difficulty string    `json:"difficulty"`
+	Size       int       `json:"size"`
+	Day        string    `json:"day,omitempty"`
+	Hinted     bool      `json:"hinted,omitempty"`
+}
+
+// Better reports whether s is ranked higher: games without hints first, then
+// the faster one.
+func (s *Score) Better(o *Score) bool {
+	if s.Hinted != o.Hinted {
+		return !s.Hinted
+	}
+	return s.Time < o.Time
+}
+
+// Submission is a score for a board with the replay of the game.
+type Submission struct {
+	Board  string `json:"board"`
+	Score  *Score `json:"score"`
+	Replay []byte `json:"replay,omitempty"`
+}
+
+// Backend is a leaderboard. Implementations are Client, Queue and Server.
+type Backend interface {
+	Submit(s *Submission) error
+	Top(board string, n int) ([]*Score, error)
+}
+
+// Flusher is a Backend which keeps submissions to send them later, e.g.
+// Queue. Flush sends the kept submissions.
+type Flusher interface {
+	Flush() error
+}
@@ -0,0 +1,260 @@
+package leaderboard
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testDate = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func newSubmission(board, name string, seconds int, hinted bool) *Submission {
+	return &Submission{
+		Board: board,
+		Score: &Score{
+			Name:       name,
+			Time:       seconds,
+			Date:       testDate,
+			Seed:       42,
+			Difficulty: "normal",
+			Size:       6,
+			Hinted:     hinted,
+		},
+		Replay: []byte{1, 2, 3},
+	}
+}
+
+// switchedServer is the reference server which answers 503 while it is down.
+type switchedServer struct {
+	*Server
+	down int32
+}
+
+func (s *switchedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&s.down) != 0 {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+		return
+	}
+	s.Server.ServeHTTP(w, r)
+}
+
+func newTestServer(t *testing.T, maxScores int) (*switchedServer, *Client) {
+	t.Helper()
+	srv, err := NewServer(maxScores, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &switchedServer{Server: srv}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return s, NewClient(ts.URL + "/")
+}
+
+func names(scores []*Score) []string {
+	var res []string
+	for _, s := range scores {
+		res = append(res, s.Name)
+	}
+	return res
+}
+
+func equalNames(got []*Score, want ...string) bool {
+	n := names(got)
+	if len(n) != len(want) {
+		return false
+	}
+	for i := range n {
+		if n[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSubmitTop(t *testing.T) {
+	_, c := newTestServer(t, 3)
+
+	for _, s := range []*Submission{
+		newSubmission("normal/6", "slow", 300, false),
+		newSubmission("normal/6", "hinted", 10, true),
+		newSubmission("normal/6", "fast", 100, false),
+		newSubmission("normal/6", "middle", 200, false),
+		newSubmission("hard/6", "other", 50, false),
+	} {
+		if err := c.Submit(s); err != nil {
+			t.Fatalf("submit %s: %v", s.Score.Name, err)
+		}
+	}
+
+	top, err := c.Top("normal/6", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// hinted games are ranked after all others and the fourth score is cut
+	if !equalNames(top, "fast", "middle", "slow") {
+		t.Errorf("got %v, want [fast middle slow]", names(top))
+	}
+	if top[0].Seed != 42 || top[0].Difficulty != "normal" || !top[0].Date.Equal(testDate) {
+		t.Errorf("score is changed by the server: %+v", top[0])
+	}
+
+	top, err = c.Top("normal/6", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalNames(top, "fast", "middle") {
+		t.Errorf("got %v, want [fast middle]", names(top))
+	}
+
+	top, err = c.Top("easy/6", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 0 {
+		t.Errorf("empty board has %v", names(top))
+	}
+}
+
+func TestSubmitRejected(t *testing.T) {
+	_, c := newTestServer(t, 10)
+
+	err := c.Submit(newSubmission("normal/6", "", 100, false))
+	if !errors.Is(err, ErrRejected) {
+		t.Errorf("submission without a name: got %v, want ErrRejected", err)
+	}
+	_, err = c.Top("", 10)
+	if !errors.Is(err, ErrRejected) {
+		t.Errorf("top without a board: got %v, want ErrRejected", err)
+	}
+}
+
+func TestQueueUnreachable(t *testing.T) {
+	srv, c := newTestServer(t, 10)
+	fileName := filepath.Join(t.TempDir(), "queue.json")
+
+	// nothing listens on the address of a closed server
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	q, err := NewQueue(NewClient(closed.URL), fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = q.Submit(newSubmission("normal/6", "first", 100, false)); err != nil {
+		t.Fatalf("submit to unreachable server: %v", err)
+	}
+	if _, err = os.Stat(fileName); err != nil {
+		t.Fatalf("queue is not saved: %v", err)
+	}
+
+	// the queue is read again by the next start of the game
+	atomic.StoreInt32(&srv.down, 1)
+	q, err = NewQueue(c, fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Len() != 1 {
+		t.Fatalf("queue has %d submissions, want 1", q.Len())
+	}
+	if err = q.Add(newSubmission("normal/6", "second", 200, false)); err != nil {
+		t.Fatal(err)
+	}
+	if err = q.Flush(); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("flush to a failing server: got %v, want ErrUnavailable", err)
+	}
+	if q.Len() != 2 {
+		t.Fatalf("queue has %d submissions, want 2", q.Len())
+	}
+
+	atomic.StoreInt32(&srv.down, 0)
+	if err = q.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if q.Len() != 0 {
+		t.Errorf("queue has %d submissions after flush", q.Len())
+	}
+	if _, err = os.Stat(fileName); !os.IsNotExist(err) {
+		t.Errorf("empty queue file is kept: %v", err)
+	}
+	top, err := c.Top("normal/6", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalNames(top, "first", "second") {
+		t.Errorf("got %v, want [first second]", names(top))
+	}
+}
+
+func TestQueueDropsRejected(t *testing.T) {
+	srv, c := newTestServer(t, 10)
+	srv.Verify = func(s *Submission) error {
+		if s.Score.Name == "cheater" {
+			return errors.New("wrong replay")
+		}
+		return nil
+	}
+	fileName := filepath.Join(t.TempDir(), "queue.json")
+	q, err := NewQueue(c, fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	atomic.StoreInt32(&srv.down, 1)
+	for _, name := range []string{"cheater", "player"} {
+		if err = q.Submit(newSubmission("normal/6", name, 100, false)); err != nil {
+			t.Fatalf("submit %s: %v", name, err)
+		}
+	}
+	if q.Len() != 2 {
+		t.Fatalf("queue has %d submissions, want 2", q.Len())
+	}
+
+	atomic.StoreInt32(&srv.down, 0)
+	if err = q.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if q.Len() != 0 {
+		t.Errorf("queue has %d submissions after flush", q.Len())
+	}
+	top, err := q.Top("normal/6", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalNames(top, "player") {
+		t.Errorf("got %v, want [player]", names(top))
+	}
+
+	// a rejected submission is returned to the caller when it is sent directly
+	err = q.Submit(newSubmission("normal/6", "cheater", 100, false))
+	if !errors.Is(err, ErrRejected) {
+		t.Errorf("got %v, want ErrRejected", err)
+	}
+	if q.Len() != 0 {
+		t.Errorf("rejected submission is queued")
+	}
+}
+
+func TestServerWriteFailure(t *testing.T) {
+	// the directory of the file does not exist, so every write fails
+	srv, err := NewServer(10, filepath.Join(t.TempDir(), "missing", "boards.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		err = srv.Submit(newSubmission("normal/6", "player", 100, false))
+		if !errors.Is(err, ErrUnavailable) {
+			t.Fatalf("got %v, want ErrUnavailable", err)
+		}
+	}
+	top, err := srv.Top("normal/6", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 0 {
+		t.Errorf("unsaved scores are on the board: %v", names(top))
+	}
+}
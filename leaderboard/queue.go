@@ -0,0 +1,155 @@
+package leaderboard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Queue keeps submissions which could not be sent while the backend is
+// unavailable and sends them before the next submission or on Flush. The
+// queue is kept in a JSON file, so it survives restarts. Len and Add do not
+// wait for the backend, so they can be called while a Flush runs.
+type Queue struct {
+	// sendMu is held while the backend is called
+	sendMu sync.Mutex
+	// mu guards pending and the file
+	mu       sync.Mutex
+	backend  Backend
+	fileName string
+	pending  []*Submission
+}
+
+var (
+	_ Backend = (*Queue)(nil)
+	_ Flusher = (*Queue)(nil)
+)
+
+// NewQueue wraps the backend. If fileName is empty, the queue is kept only
+// in memory.
+func NewQueue(backend Backend, fileName string) (*Queue, error) {
+	q := &Queue{
+		backend:  backend,
+		fileName: fileName,
+	}
+	if fileName == "" {
+		return q, nil
+	}
+	bs, err := os.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return q, nil
+	}
+	if err == nil {
+		err = json.Unmarshal(bs, &q.pending)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read queue (filename: %q): %w", fileName, err)
+	}
+	return q, nil
+}
+
+// Len returns the number of submissions waiting to be sent.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// Submit sends the pending submissions and then s. If the backend is
+// unavailable, s is queued and nil is returned.
+func (q *Queue) Submit(s *Submission) error {
+	q.sendMu.Lock()
+	defer q.sendMu.Unlock()
+
+	err := q.flush()
+	if err == nil {
+		err = q.backend.Submit(s)
+	}
+	if errors.Is(err, ErrUnavailable) {
+		return q.Add(s)
+	}
+	return err
+}
+
+// Add queues s without sending it. It is sent on the next Flush.
+func (q *Queue) Add(s *Submission) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending = append(q.pending, s)
+	return q.save()
+}
+
+// Flush sends the pending submissions. Rejected submissions are dropped.
+func (q *Queue) Flush() error {
+	q.sendMu.Lock()
+	defer q.sendMu.Unlock()
+	return q.flush()
+}
+
+// flush is called with sendMu held, so only Add changes the queue meanwhile
+// and only at its end.
+func (q *Queue) flush() error {
+	for {
+		q.mu.Lock()
+		if len(q.pending) == 0 {
+			q.mu.Unlock()
+			return nil
+		}
+		s := q.pending[0]
+		q.mu.Unlock()
+
+		err := q.backend.Submit(s)
+		if errors.Is(err, ErrUnavailable) {
+			return err
+		}
+
+		// a rejected submission is not sent again
+		q.mu.Lock()
+		q.pending = q.pending[1:]
+		err = q.save()
+		q.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (q *Queue) save() error {
+	if q.fileName == "" {
+		return nil
+	}
+	if len(q.pending) == 0 {
+		err := os.Remove(q.fileName)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove queue: %w", err)
+		}
+		return nil
+	}
+	bs, err := json.Marshal(q.pending)
+	if err != nil {
+		return fmt.Errorf("marshal queue: %w", err)
+	}
+	tmp := q.fileName + ".tmp"
+	err = os.WriteFile(tmp, bs, 0o644)
+	if err == nil {
+		err = os.Rename(tmp, q.fileName)
+	}
+	if err != nil {
+		return fmt.Errorf("write queue: %w", err)
+	}
+	return nil
+}
+
+// Top fetches the board from the backend. Pending submissions are tried
+// first, so the board includes them if the backend is available again.
+func (q *Queue) Top(board string, n int) ([]*Score, error) {
+	q.sendMu.Lock()
+	err := q.flush()
+	q.sendMu.Unlock()
+	if err != nil && !errors.Is(err, ErrUnavailable) {
+		return nil, err
+	}
+	return q.backend.Top(board, n)
+}
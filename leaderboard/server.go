@@ -0,0 +1,186 @@
+package leaderboard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Server is the reference leaderboard server. It keeps the best scores of
+// every board and optionally writes them to a JSON file after each change.
+type Server struct {
+	mu        sync.Mutex
+	boards    map[string][]*Score
+	maxScores int
+	fileName  string
+	// Verify checks a submission before it is added, e.g. its replay. A
+	// returned error rejects the submission.
+	Verify func(s *Submission) error
+}
+
+var (
+	_ Backend      = (*Server)(nil)
+	_ http.Handler = (*Server)(nil)
+)
+
+// NewServer returns a server keeping maxScores per board. If fileName is not
+// empty, the boards are read from and written to it.
+func NewServer(maxScores int, fileName string) (*Server, error) {
+	s := &Server{
+		boards:    make(map[string][]*Score),
+		maxScores: maxScores,
+		fileName:  fileName,
+	}
+	if fileName == "" {
+		return s, nil
+	}
+	bs, err := os.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err == nil {
+		err = json.Unmarshal(bs, &s.boards)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read boards (filename: %q): %w", fileName, err)
+	}
+	return s, nil
+}
+
+func (s *Server) Submit(sub *Submission) error {
+	if err := checkSubmission(sub); err != nil {
+		return fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	if s.Verify != nil {
+		if err := s.Verify(sub); err != nil {
+			return fmt.Errorf("%w: %v", ErrRejected, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := s.boards[sub.Board]
+	pos := sort.Search(len(old), func(i int) bool { return sub.Score.Better(old[i]) })
+	if pos >= s.maxScores {
+		return nil
+	}
+	scores := make([]*Score, 0, len(old)+1)
+	scores = append(scores, old[:pos]...)
+	scores = append(scores, sub.Score)
+	scores = append(scores, old[pos:]...)
+	if len(scores) > s.maxScores {
+		scores = scores[:s.maxScores]
+	}
+
+	// the board is changed only if it is written, so a repeated submission
+	// is not added twice
+	s.boards[sub.Board] = scores
+	if err := s.save(); err != nil {
+		if old == nil {
+			delete(s.boards, sub.Board)
+		} else {
+			s.boards[sub.Board] = old
+		}
+		return err
+	}
+	return nil
+}
+
+func checkSubmission(sub *Submission) error {
+	switch {
+	case sub.Board == "":
+		return errors.New("no board")
+	case sub.Score == nil:
+		return errors.New("no score")
+	case sub.Score.Name == "" || len(sub.Score.Name) > MAX_NAME_LEN:
+		return fmt.Errorf("wrong name length: %d", len(sub.Score.Name))
+	case sub.Score.Time <= 0:
+		return fmt.Errorf("wrong time: %d", sub.Score.Time)
+	}
+	return nil
+}
+
+func (s *Server) save() error {
+	if s.fileName == "" {
+		return nil
+	}
+	bs, err := json.MarshalIndent(s.boards, "", "\t")
+	if err != nil {
+		return fmt.Errorf("marshal boards: %w", err)
+	}
+	tmp := s.fileName + ".tmp"
+	err = os.WriteFile(tmp, bs, 0o644)
+	if err == nil {
+		err = os.Rename(tmp, s.fileName)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: write boards: %v", ErrUnavailable, err)
+	}
+	return nil
+}
+
+func (s *Server) Top(board string, n int) ([]*Score, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scores := s.boards[board]
+	if n < len(scores) {
+		scores = scores[:n]
+	}
+	return append([]*Score{}, scores...), nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/scores" {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		var sub Submission
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&sub)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("decode submission: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err = s.Submit(&sub); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		board := r.URL.Query().Get("board")
+		n, err := strconv.Atoi(r.URL.Query().Get("n"))
+		if board == "" || err != nil || n < 1 || n > MAX_TOP {
+			http.Error(w, "board and n from 1 to "+strconv.Itoa(MAX_TOP)+" are required", http.StatusBadRequest)
+			return
+		}
+		scores, err := s.Top(board, n)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(scores); err != nil {
+			log.Printf("Error on write scores: %v", err)
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, ErrRejected) {
+		status = http.StatusBadRequest
+	}
+	http.Error(w, err.Error(), status)
+}
@@ -0,0 +1,92 @@
+package goeinstein
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GetLegacyConfigFileName returns the config of the original game,
+// ~/.einstein/einsteinrc, or "" if the home directory is unknown.
+func GetLegacyConfigFileName() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".einstein", "einsteinrc")
+}
+
+// ImportLegacyConfig copies settings of the original game which are not set
+// yet and merges its hall of fame. It returns the number of imported values.
+func ImportLegacyConfig(fileName string) (int, error) {
+	table, err := NewTableFile(fileName)
+	if err != nil {
+		return 0, fmt.Errorf("read legacy config: %w", err)
+	}
+
+	storage := GetStorage()
+	var imported int
+	for _, k := range table.GetKeys() {
+		if strings.HasPrefix(k, "top_name_") || strings.HasPrefix(k, "top_score_") || storage.HasKey(k) {
+			continue
+		}
+		switch tp, _ := table.GetType(k); tp {
+		case IntegerType, DoubleType:
+			storage.SetInt(k, table.GetInt(k, 0))
+		case StringType:
+			storage.SetString(k, table.GetString(k, ""))
+		default:
+			continue
+		}
+		imported++
+	}
+
+	scores := NewHallOfFame()
+	for i := 0; i < MAX_SCORES; i++ {
+		score := table.GetInt("top_score_"+ToString(i), -1)
+		if score < 0 {
+			break
+		}
+		entry := &TopScoreEntry{
+			Name:       table.GetString("top_name_"+ToString(i), ""),
+			Time:       score,
+			Difficulty: DIFFICULTY_NORMAL,
+			Size:       PUZZLE_SIZE,
+		}
+		if scores.Add(entry) >= 0 {
+			imported++
+		}
+	}
+	scores.Save()
+	storage.Flush()
+	return imported, nil
+}
+
+// ImportLegacyConfigOnce imports the config of the original game into the
+// default profile on the first run. Errors are only logged.
+func ImportLegacyConfigOnce() {
+	if GetProfiles().Current != DEFAULT_PROFILE {
+		return
+	}
+	storage := GetStorage()
+	if storage.GetInt("legacyImported", 0) != 0 {
+		return
+	}
+	storage.SetInt("legacyImported", 1)
+
+	fileName := GetLegacyConfigFileName()
+	if fileName == "" {
+		return
+	}
+	if _, err := os.Stat(fileName); err != nil {
+		return
+	}
+	n, err := ImportLegacyConfig(fileName)
+	if err != nil {
+		log.Printf("Error on import legacy config: %v", err)
+		return
+	}
+	log.Printf("%d values are imported from %q", n, fileName)
+}
@@ -0,0 +1,305 @@
+package goeinstein
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// Save files of the original game have no header and the same layout as the
+// version 0 payload, except that strings are NUL-terminated.
+
+// GetLegacySavesPath returns ~/.einstein/save of the original game, or "" if
+// the home directory is unknown.
+func GetLegacySavesPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".einstein", "save")
+}
+
+// GetLegacyResourceFileName returns einstein.res of an installed original
+// game or "" if there is none.
+func GetLegacyResourceFileName() string {
+	paths := []string{
+		"/usr/share/einstein/res/einstein.res",
+		"/usr/local/share/einstein/res/einstein.res",
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append([]string{filepath.Join(home, ".einstein", "einstein.res")}, paths...)
+	}
+	for _, p := range paths {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// GetImportedResourceFileName is where einstein.res is kept after import.
+func GetImportedResourceFileName() string {
+	return filepath.Join(GetDataDir(), "einstein.res")
+}
+
+// ConvertLegacySave converts a save file of the original game to the payload
+// of the current version.
+func ConvertLegacySave(bs []byte) ([]byte, error) {
+	stream := bytes.NewReader(bs)
+	name, err := ReadLegacyString(stream)
+	if err != nil {
+		return nil, fmt.Errorf("read name: %w", err)
+	}
+	var puzzle SolvedPuzzle
+	err = LoadPuzzle(&puzzle, stream)
+	if err != nil {
+		return nil, fmt.Errorf("read puzzle: %w", err)
+	}
+	rules, err := loadLegacyRules(stream)
+	if err != nil {
+		return nil, err
+	}
+	rest := bs[len(bs)-stream.Len():]
+
+	var v0 bytes.Buffer
+	WriteString(&v0, name)
+	SavePuzzle(&puzzle, &v0)
+	SaveRules(&rules, &v0)
+	v0.Write(rest)
+
+	payload, err := ReadSavePayload(v0.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	err = checkSavePayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func loadLegacyRules(stream io.Reader) (Rules, error) {
+	no, err := ReadInt(stream)
+	if err != nil {
+		return nil, fmt.Errorf("read rules count: %w", err)
+	}
+	if no < 0 || no > PUZZLE_SIZE*PUZZLE_SIZE*PUZZLE_SIZE {
+		return nil, fmt.Errorf("wrong rules count: %d", no)
+	}
+
+	var rules Rules
+	for i := 0; i < no; i++ {
+		ruleType, err := ReadLegacyString(stream)
+		if err != nil {
+			return nil, fmt.Errorf("read rule type: %w", err)
+		}
+		t, ok := GetRuleType(ruleType)
+		if !ok {
+			return nil, fmt.Errorf("invalid rule type: %q", ruleType)
+		}
+		r, err := t.Load(stream)
+		if err != nil {
+			return nil, fmt.Errorf("load %q rule: %w", ruleType, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// checkSavePayload reads the parts of the game which can be read without the
+// screen: possibilities, both hints lists, the watch, the move log and the
+// undo history.
+func checkSavePayload(payload []byte) error {
+	stream := bytes.NewReader(payload)
+	hints, err := skipSaveMoves(stream, NewSaveInfoStream)
+	if err != nil {
+		return err
+	}
+	if _, err = NewUndoHistoryStream(stream, hints[0], hints[1]); err != nil {
+		return err
+	}
+	if stream.Len() > 0 {
+		return fmt.Errorf("unexpected %d bytes at the end of the game", stream.Len())
+	}
+	return nil
+}
+
+// skipSaveMoves reads the payload up to the end of the move log, the save
+// info is read with readInfo. It returns the sizes of both hints lists.
+func skipSaveMoves(stream *bytes.Reader, readInfo func(io.Reader) (*SaveInfo, error)) ([2]int, error) {
+	var hints [2]int
+	_, err := ReadString(stream)
+	if err != nil {
+		return hints, err
+	}
+	_, err = readInfo(stream)
+	if err != nil {
+		return hints, err
+	}
+	var puzzle SolvedPuzzle
+	var rules Rules
+	if err = LoadPuzzle(&puzzle, stream); err != nil {
+		return hints, err
+	}
+	if err = LoadRules(&rules, stream); err != nil {
+		return hints, err
+	}
+	if _, err = NewPossibilitiesStream(stream); err != nil {
+		return hints, err
+	}
+	for i := range hints {
+		cnt, err := ReadInt(stream)
+		if err != nil {
+			return hints, fmt.Errorf("read hints: %w", err)
+		}
+		if cnt < 0 || cnt > len(rules) || stream.Len() < cnt*8+4 {
+			return hints, fmt.Errorf("wrong hints count: %d", cnt)
+		}
+		_, _ = stream.Seek(int64(cnt*8+4), io.SeekCurrent)
+		hints[i] = cnt
+	}
+	if _, err = ReadInt(stream); err != nil {
+		return hints, fmt.Errorf("read watch: %w", err)
+	}
+	if _, err = NewMoveLogStream(stream); err != nil {
+		return hints, err
+	}
+	return hints, nil
+}
+
+// ImportLegacySaves converts save files of the original game into the
+// saves list. Games which were imported before are skipped. It returns the
+// numbers of imported and failed files.
+func ImportLegacySaves(list *SaveList) (imported, failed int) {
+	path := GetLegacySavesPath()
+	if path == "" {
+		return 0, 0
+	}
+	files, err := filepath.Glob(filepath.Join(path, "*.sav"))
+	if err != nil {
+		log.Printf("Error on list legacy saves: %v", err)
+		return 0, 0
+	}
+
+	existing := make(map[string]bool)
+	for _, sg := range list.saves {
+		if bs, err := os.ReadFile(sg.GetFileName()); err == nil {
+			if payload, err := ReadSavePayload(bs); err == nil {
+				existing[string(payload)] = true
+			}
+		}
+	}
+
+	for _, f := range files {
+		bs, err := os.ReadFile(f)
+		if err != nil {
+			log.Printf("Error on import %q: %v", f, err)
+			failed++
+			continue
+		}
+		payload, err := ConvertLegacySave(bs)
+		if err != nil {
+			log.Printf("Error on import %q: %v", f, err)
+			failed++
+			continue
+		}
+		if existing[string(payload)] {
+			continue
+		}
+
+		var out bytes.Buffer
+		err = writeSavePayload(&out, payload)
+		if err == nil {
+			err = WriteFileAtomic(list.NewFileName(), out.Bytes(), 0o644)
+		}
+		if err != nil {
+			log.Printf("Error on import %q: %v", f, err)
+			failed++
+			continue
+		}
+		existing[string(payload)] = true
+		imported++
+	}
+	return imported, failed
+}
+
+// ImportLegacyResources copies einstein.res of the original game to the data
+// directory and adds it to the resources. It returns false if there is no
+// archive or it is broken.
+func ImportLegacyResources() bool {
+	fileName := GetLegacyResourceFileName()
+	if fileName == "" {
+		return false
+	}
+	if _, err := OpenResourceArchive(fileName); err != nil {
+		log.Printf("Error on import resources: %v", err)
+		return false
+	}
+	dst := GetImportedResourceFileName()
+	bs, err := os.ReadFile(fileName)
+	if err == nil {
+		err = WriteFileAtomic(dst, bs, 0o644)
+	}
+	if err != nil {
+		log.Printf("Error on import resources: %v", err)
+		return false
+	}
+	return LoadImportedResources()
+}
+
+// LoadImportedResources adds the imported einstein.res to the resources.
+func LoadImportedResources() bool {
+	fileName := GetImportedResourceFileName()
+	if _, err := os.Stat(fileName); err != nil {
+		return false
+	}
+	a, err := OpenResourceArchive(fileName)
+	if err != nil {
+		log.Printf("Error on load imported resources: %v", err)
+		return false
+	}
+	for _, existing := range resources.archives {
+		if existing.GetFileName() == fileName {
+			return true
+		}
+	}
+	resources.AddArchive(a)
+	return true
+}
+
+type ImportCommand struct {
+	list       *SaveList
+	font       *Font
+	parentArea *Area
+}
+
+var _ Command = (*ImportCommand)(nil)
+
+func NewImportCommand(list *SaveList, font *Font, parentArea *Area) *ImportCommand {
+	i := &ImportCommand{}
+	i.list = list
+	i.font = font
+	i.parentArea = parentArea
+	return i
+}
+
+func (i *ImportCommand) DoAction() {
+	imported, failed := ImportLegacySaves(i.list)
+	withRes := ImportLegacyResources()
+
+	text := msg("importNothing")
+	if imported > 0 || failed > 0 {
+		text = fmt.Sprintf("%s: %d", msg("imported"), imported)
+	}
+	if failed > 0 {
+		text += fmt.Sprintf(", %s: %d", msg("importFailed"), failed)
+	}
+	if withRes {
+		text += ", " + msg("importRes")
+	}
+	ShowMessageWindow(i.parentArea, "redpattern.bmp", 400, 80, i.font, 255, 255, 255, text)
+	i.parentArea.FinishEventLoop()
+}
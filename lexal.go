@@ -0,0 +1,214 @@
+package goeinstein
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type LexemeType int
+
+const (
+	UnknownLexeme LexemeType = iota
+	IdentLexeme
+	StringLexeme
+	IntegerLexeme
+	FloatLexeme
+	SymbolLexeme
+	EofLexeme
+)
+
+type Lexeme struct {
+	lexType   LexemeType
+	content   string
+	line, pos int
+}
+
+func NewLexeme(tp LexemeType, content string, line, pos int) *Lexeme {
+	return &Lexeme{
+		lexType: tp,
+		content: content,
+		line:    line,
+		pos:     pos,
+	}
+}
+
+func (l *Lexeme) GetType() LexemeType { return l.lexType }
+func (l *Lexeme) GetContent() string  { return l.content }
+func (l *Lexeme) GetLine() int        { return l.line }
+func (l *Lexeme) GetPos() int         { return l.pos }
+
+func (l *Lexeme) IsSymbol(s string) bool {
+	return l.lexType == SymbolLexeme && l.content == s
+}
+
+// Lexal splits the text of the config format of the original game into
+// lexemes. Lines and positions are 1-based, "#" starts a comment.
+type Lexal struct {
+	text      []rune
+	pos       int
+	line, col int
+}
+
+func NewLexal(text string) *Lexal {
+	return &Lexal{
+		text: []rune(text),
+		line: 1,
+		col:  1,
+	}
+}
+
+// PosToStr returns the position for error messages.
+func (l *Lexal) PosToStr(line, pos int) string {
+	return fmt.Sprintf("(%d:%d)", line, pos)
+}
+
+func (l *Lexal) peek() rune {
+	if l.pos >= len(l.text) {
+		return 0
+	}
+	return l.text[l.pos]
+}
+
+func (l *Lexal) next() rune {
+	ch := l.text[l.pos]
+	l.pos++
+	if ch == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return ch
+}
+
+func (l *Lexal) skipSpaces() {
+	for l.pos < len(l.text) {
+		ch := l.peek()
+		switch {
+		case unicode.IsSpace(ch):
+			l.next()
+		case ch == '#':
+			for l.pos < len(l.text) && l.peek() != '\n' {
+				l.next()
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (l *Lexal) GetNext() (*Lexeme, error) {
+	l.skipSpaces()
+	line, pos := l.line, l.col
+	if l.pos >= len(l.text) {
+		return NewLexeme(EofLexeme, "", line, pos), nil
+	}
+
+	ch := l.peek()
+	switch {
+	case ch == '"':
+		return l.readString(line, pos)
+	case isIdentStart(ch):
+		var sb strings.Builder
+		for l.pos < len(l.text) && isIdentChar(l.peek()) {
+			sb.WriteRune(l.next())
+		}
+		return NewLexeme(IdentLexeme, sb.String(), line, pos), nil
+	case ch == '-' || ch == '+' || ch == '.' || unicode.IsDigit(ch):
+		return l.readNumber(line, pos)
+	case strings.ContainsRune("{}=;,", ch):
+		l.next()
+		return NewLexeme(SymbolLexeme, string(ch), line, pos), nil
+	}
+	return nil, fmt.Errorf("invalid character %q at %s", ch, l.PosToStr(line, pos))
+}
+
+func (l *Lexal) readString(line, pos int) (*Lexeme, error) {
+	var sb strings.Builder
+	l.next()
+	for l.pos < len(l.text) {
+		ch := l.next()
+		switch ch {
+		case '"':
+			return NewLexeme(StringLexeme, sb.String(), line, pos), nil
+		case '\\':
+			if l.pos >= len(l.text) {
+				break
+			}
+			switch esc := l.next(); esc {
+			case 'n':
+				sb.WriteRune('\n')
+			case 'r':
+				sb.WriteRune('\r')
+			case 't':
+				sb.WriteRune('\t')
+			default:
+				sb.WriteRune(esc)
+			}
+		default:
+			sb.WriteRune(ch)
+		}
+	}
+	return nil, fmt.Errorf("string started at %s is not finished", l.PosToStr(line, pos))
+}
+
+func (l *Lexal) readNumber(line, pos int) (*Lexeme, error) {
+	var sb strings.Builder
+	tp := IntegerLexeme
+	if ch := l.peek(); ch == '-' || ch == '+' {
+		sb.WriteRune(l.next())
+	}
+	var digits bool
+	for l.pos < len(l.text) {
+		ch := l.peek()
+		switch {
+		case unicode.IsDigit(ch):
+			digits = true
+		case ch == '.' || ch == 'e' || ch == 'E':
+			tp = FloatLexeme
+		case (ch == '-' || ch == '+') && strings.HasSuffix(strings.ToLower(sb.String()), "e"):
+		default:
+			if !digits {
+				return nil, fmt.Errorf("invalid number at %s", l.PosToStr(line, pos))
+			}
+			return NewLexeme(tp, sb.String(), line, pos), nil
+		}
+		sb.WriteRune(l.next())
+	}
+	if !digits {
+		return nil, fmt.Errorf("invalid number at %s", l.PosToStr(line, pos))
+	}
+	return NewLexeme(tp, sb.String(), line, pos), nil
+}
+
+func isIdentStart(ch rune) bool {
+	return ch == '_' || unicode.IsLetter(ch)
+}
+
+func isIdentChar(ch rune) bool {
+	return isIdentStart(ch) || unicode.IsDigit(ch)
+}
+
+// EscapeString quotes the string for the config format.
+func EscapeString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, ch := range s {
+		switch ch {
+		case '"', '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(ch)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			sb.WriteRune(ch)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
@@ -0,0 +1,73 @@
+package goeinstein
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var ErrAlreadyRunning = errors.New("another instance of the game is running")
+
+func GetLockFileName() string {
+	return filepath.Join(GetConfigDir(), "einstein.lock")
+}
+
+// LockInstance creates the lock file with the pid of the game. A lock file of
+// a process which does not exist anymore is replaced. The returned function
+// removes the lock file.
+func LockInstance() (func(), error) {
+	fileName := GetLockFileName()
+	pid := os.Getpid()
+	for i := 0; i < 2; i++ {
+		f, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			_, err = f.WriteString(strconv.Itoa(pid))
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(fileName)
+				return nil, fmt.Errorf("write lock file (filename: %q): %w", fileName, err)
+			}
+			return func() { unlockInstance(fileName, pid) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("create lock file (filename: %q): %w", fileName, err)
+		}
+
+		owner, err := readLockPid(fileName)
+		if err == nil && owner != pid && processExists(owner) {
+			return nil, fmt.Errorf("%w (pid: %d, lock file: %q)", ErrAlreadyRunning, owner, fileName)
+		}
+		log.Printf("Remove stale lock file %q", fileName)
+		err = os.Remove(fileName)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("remove stale lock file (filename: %q): %w", fileName, err)
+		}
+	}
+	return nil, fmt.Errorf("%w (lock file: %q)", ErrAlreadyRunning, fileName)
+}
+
+func readLockPid(fileName string) (int, error) {
+	bs, err := os.ReadFile(fileName)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(bs)))
+}
+
+// unlockInstance removes the lock file if it is still owned by the game.
+func unlockInstance(fileName string, pid int) {
+	owner, err := readLockPid(fileName)
+	if err != nil || owner != pid {
+		return
+	}
+	err = os.Remove(fileName)
+	if err != nil {
+		log.Printf("Error on remove lock file: %v", err)
+	}
+}
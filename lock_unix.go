@@ -0,0 +1,11 @@
+//go:build !windows
+
+package goeinstein
+
+import "syscall"
+
+// processExists sends the null signal to check the process.
+func processExists(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package goeinstein
+
+import "os"
+
+// processExists opens the process, which fails if there is no such process.
+func processExists(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
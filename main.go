@@ -13,7 +13,7 @@ var screen *Screen = NewScreen()
 // var rndGen Random
 
 var atexit = []func(){
-	GetStorage().Close,
+	CloseStorage,
 }
 
 func initScreen() {
@@ -41,26 +41,42 @@ func initScreen() {
 
 func initAudio() {
 	sound = NewSound()
-	sound.SetVolume(float32(GetStorage().GetInt("volume", 20)) / 100.0)
+	sound.SetVolume(float32(options.Volume.Value()) / 100.0)
 }
 
-func Main() error {
+func Main() (err error) {
+	MigrateLegacyDir()
+	// released after the storage is flushed by atexit
+	unlock, err := LockInstance()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	defer func() {
 		for i := len(atexit) - 1; i >= 0; i-- {
 			atexit[i]()
 		}
 	}()
+	// runs before atexit, while the screen is still usable
+	defer func() {
+		if r := recover(); r != nil {
+			err = HandleCrash(r)
+		}
+	}()
 
 	runtime.LockOSThread()
 
-	EnsureDirExists("./einstein")
+	ImportLegacyConfigOnce()
+	LoadImportedResources()
+	options = NewOptions()
 
 	// LoadResources()
 	initScreen()
 	initAudio()
+	// the storage is replaced when the profile is switched
+	atexit = append(atexit, screen.DoneCursors, func() { GetStorage().Flush() })
+	FlushLeaderboard()
 	Menu()
-	GetStorage().Flush()
-
-	screen.DoneCursors()
 	return nil
 }
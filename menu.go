@@ -1,6 +1,9 @@
 package goeinstein
 
 import (
+	"log"
+	"time"
+
 	"github.com/veandco/go-sdl2/sdl"
 )
 
@@ -24,6 +27,8 @@ func (m *MenuBackground) Draw() {
 	s = "http://games.flowix.com"
 	width = urlFont.GetWidth(s)
 	urlFont.Draw((screen.GetWidth()-width)/2, 60, 255, 255, 0, true, s)
+	profileFont := NewFont("laudcn2.ttf", 16)
+	profileFont.Draw(20, 570, 255, 255, 255, true, msg("player")+": "+GetProfiles().GetCurrent().GetName())
 	screen.AddRegionToUpdate(0, 0, screen.GetWidth(), screen.GetHeight())
 }
 
@@ -38,10 +43,63 @@ func NewNewGameCommand(a *Area) *NewGameCommand {
 }
 
 func (n *NewGameCommand) DoAction() {
-	game = NewGame()
-	game.Run()
-	n.area.UpdateMouse()
-	n.area.Draw()
+	StartGame(n.area, NewGenConfigDifficulty(GetDifficulty()))
+}
+
+// StartGame generates a new game with cfg and runs it.
+func StartGame(area *Area, cfg *GenConfig) {
+	g, err := NewGameSeed(time.Now().Unix(), cfg)
+	if err != nil {
+		log.Printf("Error on new game: %v", err)
+		area.Draw()
+		font := NewFont("laudcn2.ttf", 16)
+		ShowMessageWindow(area, "redpattern.bmp", 500, 80, font, 255, 255, 255, msg("genError"))
+	} else {
+		game = g
+		game.Run()
+	}
+	area.UpdateMouse()
+	area.Draw()
+}
+
+type DailyCommand struct {
+	area *Area
+}
+
+var _ Command = (*DailyCommand)(nil)
+
+func NewDailyCommand(a *Area) *DailyCommand {
+	d := &DailyCommand{}
+	d.area = a
+	return d
+}
+
+func (d *DailyCommand) DoAction() {
+	ShowDailyWindow(d.area)
+	d.area.UpdateMouse()
+	d.area.Draw()
+}
+
+type CustomGameCommand struct {
+	area *Area
+}
+
+var _ Command = (*CustomGameCommand)(nil)
+
+func NewCustomGameCommand(a *Area) *CustomGameCommand {
+	c := &CustomGameCommand{}
+	c.area = a
+	return c
+}
+
+func (c *CustomGameCommand) DoAction() {
+	cfg := ShowCustomGameWindow(c.area)
+	if cfg == nil {
+		c.area.UpdateMouse()
+		c.area.Draw()
+		return
+	}
+	StartGame(c.area, cfg)
 }
 
 type LoadGameCommand struct {
@@ -66,6 +124,37 @@ func (l *LoadGameCommand) DoAction() {
 	l.area.Draw()
 }
 
+type ContinueCommand struct {
+	area *Area
+}
+
+var _ Command = (*ContinueCommand)(nil)
+
+func NewContinueCommand(a *Area) *ContinueCommand {
+	c := &ContinueCommand{}
+	c.area = a
+	return c
+}
+
+func (c *ContinueCommand) DoAction() {
+	font := NewFont("laudcn2.ttf", 16)
+	if !HasAutosave() {
+		ShowMessageWindow(c.area, "redpattern.bmp", 300, 80, font, 255, 255, 255, msg("noAutosave"))
+	} else {
+		g, err := LoadAutosave()
+		if err != nil {
+			log.Printf("Error on continue game: %v", err)
+			ShowMessageWindow(c.area, "redpattern.bmp", 300, 80, font, 255, 255, 255, msg("corruptedSave"))
+		} else {
+			game = g
+			game.Run()
+			game.Close()
+		}
+	}
+	c.area.UpdateMouse()
+	c.area.Draw()
+}
+
 type TopScoresCommand struct {
 	area *Area
 }
@@ -79,12 +168,48 @@ func NewTopScoresCommand(a *Area) *TopScoresCommand {
 }
 
 func (l *TopScoresCommand) DoAction() {
-	scores := NewTopScores()
+	scores := NewHallOfFame()
 	ShowScoresWindow(l.area, scores)
 	l.area.UpdateMouse()
 	l.area.Draw()
 }
 
+type StatisticsCommand struct {
+	area *Area
+}
+
+var _ Command = (*StatisticsCommand)(nil)
+
+func NewStatisticsCommand(a *Area) *StatisticsCommand {
+	l := &StatisticsCommand{}
+	l.area = a
+	return l
+}
+
+func (l *StatisticsCommand) DoAction() {
+	ShowStatisticsWindow(l.area)
+	l.area.UpdateMouse()
+	l.area.Draw()
+}
+
+type ProfilesCommand struct {
+	area *Area
+}
+
+var _ Command = (*ProfilesCommand)(nil)
+
+func NewProfilesCommand(a *Area) *ProfilesCommand {
+	l := &ProfilesCommand{}
+	l.area = a
+	return l
+}
+
+func (l *ProfilesCommand) DoAction() {
+	ShowProfilesWindow(l.area)
+	l.area.UpdateMouse()
+	l.area.Draw()
+}
+
 type RulesCommand struct {
 	area *Area
 }
@@ -172,25 +297,41 @@ func Menu() {
 	area.Add(NewMenuBackground())
 	area.Draw()
 
+	profilesCmd := NewProfilesCommand(area)
+	area.Add(NewMenuButton(220, font, msg("profiles"), profilesCmd))
+	continueCmd := NewContinueCommand(area)
+	area.Add(NewMenuButton(250, font, msg("continue"), continueCmd))
 	newGameCmd := NewNewGameCommand(area)
-	area.Add(NewMenuButton(340, font, msg("newGame"), newGameCmd))
+	area.Add(NewMenuButton(280, font, msg("newGame"), newGameCmd))
+	dailyCmd := NewDailyCommand(area)
+	area.Add(NewMenuButton(310, font, msg("dailyPuzzle"), dailyCmd))
+	customGameCmd := NewCustomGameCommand(area)
+	area.Add(NewMenuButton(340, font, msg("customGame"), customGameCmd))
 	loadGameCmd := NewLoadGameCommand(area)
 	area.Add(NewMenuButton(370, font, msg("loadGame"), loadGameCmd))
 	topScoresCmd := NewTopScoresCommand(area)
 	area.Add(NewMenuButton(400, font, msg("topScores"), topScoresCmd))
+	statisticsCmd := NewStatisticsCommand(area)
+	area.Add(NewMenuButton(430, font, msg("statistics"), statisticsCmd))
 	rulesCmd := NewRulesCommand(area)
-	area.Add(NewMenuButton(430, font, msg("rules"), rulesCmd))
+	area.Add(NewMenuButton(460, font, msg("rules"), rulesCmd))
 	optionsCmd := NewOptionsCommand(area)
-	area.Add(NewMenuButton(460, font, msg("options"), optionsCmd))
+	area.Add(NewMenuButton(490, font, msg("options"), optionsCmd))
 	aboutCmd := NewAboutCommand(area)
-	area.Add(NewMenuButton(490, font, msg("about"), aboutCmd))
+	area.Add(NewMenuButton(520, font, msg("about"), aboutCmd))
 	exitMenuCmd := NewExitCommand(area)
-	area.Add(NewMenuButton(520, font, msg("exit"), exitMenuCmd))
+	area.Add(NewMenuButton(550, font, msg("exit"), exitMenuCmd))
 	area.Add(NewKeyAccel(sdl.K_ESCAPE, exitMenuCmd))
 
 	area.Draw()
 	screen.AddRegionToUpdate(0, 0, screen.GetWidth(), screen.GetHeight())
 	screen.Flush()
 
+	if len(GetProfiles().Profiles) > 1 {
+		ShowProfilesWindow(area)
+		area.Draw()
+	}
+	RestoreCrashSave(area)
+	area.Draw()
 	area.Run()
 }
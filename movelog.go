@@ -0,0 +1,201 @@
+package goeinstein
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+//nolint:golint,nosnakecase,stylecheck
+const (
+	MOVE_SET     = 1
+	MOVE_EXCLUDE = 2
+	// MOVE_HINT_EXCLUDE and MOVE_HINT_RESTORE are clicks on the hints lists,
+	// Row is SHOW_VERT or SHOW_HORIZ and Col is the number of the hint.
+	MOVE_HINT_EXCLUDE = 3
+	MOVE_HINT_RESTORE = 4
+	// MOVE_SWITCH shows the excluded hints or the active ones.
+	MOVE_SWITCH = 5
+	MOVE_PAUSE  = 6
+	MOVE_UNDO   = 7
+	MOVE_REDO   = 8
+
+	// MAX_MOVES limits move logs read from files.
+	MAX_MOVES = 100000
+)
+
+// Move is an action of the player. A click on a card sets it with MOVE_SET or
+// excludes it with MOVE_EXCLUDE, AutoHint is true if the rules were applied
+// after it. Other actions have no card.
+type Move struct {
+	Kind      int
+	Col, Row  int
+	Card      Card
+	AutoHint  bool
+	ElapsedMs int // time of the watch when the move was made
+}
+
+// IsCardMove reports whether the move is a click on a card.
+func (m *Move) IsCardMove() bool { return m.Kind == MOVE_SET || m.Kind == MOVE_EXCLUDE }
+
+// IsUndoable reports whether the move changes the puzzle or the hints lists,
+// so it is undone with MOVE_UNDO.
+func (m *Move) IsUndoable() bool {
+	return m.IsCardMove() || m.Kind == MOVE_HINT_EXCLUDE || m.Kind == MOVE_HINT_RESTORE
+}
+
+// MoveLog is the moves of the game since its puzzle was opened. InitialHints
+// is true if the rules were applied when the puzzle was opened.
+type MoveLog struct {
+	InitialHints bool
+	Moves        []Move
+}
+
+func NewMoveLog(initialHints bool) *MoveLog {
+	return &MoveLog{InitialHints: initialHints}
+}
+
+func (l *MoveLog) Add(m Move) { l.Moves = append(l.Moves, m) }
+
+// IsHinted reports whether the rules were applied by the game.
+func (l *MoveLog) IsHinted() bool {
+	if l.InitialHints {
+		return true
+	}
+	for _, m := range l.Moves {
+		if m.AutoHint {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Move) Save(stream io.Writer) {
+	WriteInt(stream, m.Kind)
+	WriteInt(stream, m.Col)
+	WriteInt(stream, m.Row)
+	m.Card.WriteTo(stream)
+	WriteInt(stream, boolToInt[m.AutoHint])
+	WriteInt(stream, m.ElapsedMs)
+}
+
+func NewMoveStream(stream io.Reader) (*Move, error) {
+	var m Move
+	var card, autoHint int
+	var err error
+	for _, v := range []*int{&m.Kind, &m.Col, &m.Row, &card, &autoHint, &m.ElapsedMs} {
+		if *v, err = ReadInt(stream); err != nil {
+			return nil, fmt.Errorf("read move: %w", err)
+		}
+	}
+	if m.IsCardMove() && (card < 1 || card > PUZZLE_SIZE) || !m.IsCardMove() && card != 0 {
+		return nil, fmt.Errorf("wrong card: %d", card)
+	}
+	m.Card = Card(card)
+	m.AutoHint = autoHint != 0
+	return &m, nil
+}
+
+func (l *MoveLog) Save(stream io.Writer) {
+	WriteInt(stream, boolToInt[l.InitialHints])
+	WriteInt(stream, len(l.Moves))
+	for i := range l.Moves {
+		l.Moves[i].Save(stream)
+	}
+}
+
+func NewMoveLogStream(stream io.Reader) (*MoveLog, error) {
+	hints, err := ReadInt(stream)
+	if err != nil {
+		return nil, fmt.Errorf("read move log: %w", err)
+	}
+	cnt, err := ReadInt(stream)
+	if err != nil {
+		return nil, fmt.Errorf("read moves count: %w", err)
+	}
+	if cnt < 0 || cnt > MAX_MOVES {
+		return nil, fmt.Errorf("wrong moves count: %d", cnt)
+	}
+	l := &MoveLog{InitialHints: hints != 0}
+	for i := 0; i < cnt; i++ {
+		m, err := NewMoveStream(stream)
+		if err != nil {
+			return nil, err
+		}
+		l.Moves = append(l.Moves, *m)
+	}
+	return l, nil
+}
+
+// Encode returns the log in the format of save files.
+func (l *MoveLog) Encode() []byte {
+	var buf bytes.Buffer
+	l.Save(&buf)
+	return buf.Bytes()
+}
+
+func DecodeMoveLog(bs []byte) (*MoveLog, error) {
+	stream := bytes.NewReader(bs)
+	l, err := NewMoveLogStream(stream)
+	if err != nil {
+		return nil, err
+	}
+	if stream.Len() > 0 {
+		return nil, fmt.Errorf("unexpected %d bytes after the moves", stream.Len())
+	}
+	return l, nil
+}
+
+// MoveRecorder is told about every move of the player.
+type MoveRecorder interface {
+	RecordMove(m Move)
+}
+
+// VerifyMoveLog generates the puzzle of the seed and the difficulty again,
+// checks it with the solver and replays the moves: every move must be
+// allowed, keep the puzzle solvable and the last one must solve it in not
+// more than seconds.
+func VerifyMoveLog(l *MoveLog, seed int64, difficulty string, seconds int) error {
+	puzzle, rules, err := GenLoggedPuzzle(seed, difficulty)
+	if err != nil {
+		return err
+	}
+	cnf, err := EncodeCNF(rules, PUZZLE_SIZE)
+	if err != nil {
+		return err
+	}
+	lits, ok := SolveCNF(cnf)
+	if !ok {
+		return errors.New("puzzle is unsatisfiable")
+	}
+	solved, err := DecodeCNFSolution(lits)
+	if err != nil {
+		return err
+	}
+	if *solved != *puzzle || !IsUniqueCNF(cnf) {
+		return errors.New("puzzle has another solution")
+	}
+
+	r := NewMoveReplayer(rules, l.InitialHints)
+	var last int
+	for i, m := range l.Moves {
+		if m.ElapsedMs < last {
+			return fmt.Errorf("move %d is made before the previous one", i)
+		}
+		last = m.ElapsedMs
+		if err = r.Apply(m); err != nil {
+			return fmt.Errorf("move %d: %w", i, err)
+		}
+		if !r.GetPossibilities().IsValid(puzzle) {
+			return fmt.Errorf("move %d is wrong", i)
+		}
+	}
+	if !r.GetPossibilities().IsSolved() {
+		return errors.New("puzzle is not solved by the moves")
+	}
+	if last/1000 > seconds {
+		return fmt.Errorf("moves took %d seconds, more than %d", last/1000, seconds)
+	}
+	return nil
+}
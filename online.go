@@ -0,0 +1,184 @@
+package goeinstein
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/vkd/goeinstein/leaderboard"
+)
+
+// leaderboardQueue is shared by all halls of fame, so a flush running in the
+// background does not overwrite the queue file of another one.
+var leaderboardQueue struct {
+	url, fileName string
+	queue         *leaderboard.Queue
+}
+
+// GetLeaderboard returns the online leaderboard of the options or nil if no
+// server is set. Scores are queued in the profile directory while the server
+// is unreachable.
+func GetLeaderboard() leaderboard.Backend {
+	if options == nil {
+		// the legacy config is imported before the options are read
+		return nil
+	}
+	url := options.OnlineServer.Value()
+	if url == "" {
+		return nil
+	}
+	fileName := filepath.Join(GetProfileDataDir(), "leaderboard-queue.json")
+	if leaderboardQueue.queue != nil && leaderboardQueue.url == url && leaderboardQueue.fileName == fileName {
+		return leaderboardQueue.queue
+	}
+	q, err := leaderboard.NewQueue(leaderboard.NewClient(url), fileName)
+	if err != nil {
+		log.Printf("Error on open leaderboard queue: %v", err)
+		return nil
+	}
+	leaderboardQueue.url = url
+	leaderboardQueue.fileName = fileName
+	leaderboardQueue.queue = q
+	return q
+}
+
+func toLeaderboardScore(e *TopScoreEntry) *leaderboard.Score {
+	return &leaderboard.Score{
+		Name:       e.Name,
+		Time:       e.Time,
+		Date:       e.Date,
+		Seed:       e.Seed,
+		Difficulty: e.Difficulty,
+		Size:       e.Size,
+		Day:        e.Day,
+		Hinted:     e.Hinted,
+	}
+}
+
+func fromLeaderboardScore(s *leaderboard.Score) *TopScoreEntry {
+	return &TopScoreEntry{
+		Name:       s.Name,
+		Time:       s.Time,
+		Date:       s.Date,
+		Seed:       s.Seed,
+		Difficulty: s.Difficulty,
+		Size:       s.Size,
+		Day:        s.Day,
+		Hinted:     s.Hinted,
+	}
+}
+
+// FlushLeaderboard sends the scores queued in the previous runs of the game
+// in the background.
+func FlushLeaderboard() {
+	f, ok := GetLeaderboard().(leaderboard.Flusher)
+	if !ok {
+		return
+	}
+	go func() {
+		if err := f.Flush(); err != nil {
+			log.Printf("Error on submit queued scores: %v", err)
+		}
+	}()
+}
+
+// Submit sends the entry to the online leaderboard if there is one. The
+// moves of the entry are replayed first and the entry is sent only if they
+// solve the puzzle, both in the background. Errors are only logged, the
+// leaderboard queues scores while the server is unreachable.
+func (h *HallOfFame) Submit(e *TopScoreEntry) {
+	if h.online == nil {
+		return
+	}
+	c := e.check()
+	online := h.online
+	sub := &leaderboard.Submission{
+		Board:  boardKey(e.Difficulty, e.Size, e.Day),
+		Score:  toLeaderboardScore(e),
+		Replay: e.Moves,
+	}
+	go func() {
+		<-c.done
+		if c.err != nil {
+			log.Printf("Error on verify score, it is not submitted: %v", c.err)
+			return
+		}
+		if err := online.Submit(sub); err != nil {
+			log.Printf("Error on submit score: %v", err)
+		}
+	}()
+}
+
+// VerifySubmission checks that the replay of the submission solves its puzzle
+// in its time and that the board is of the puzzle. It is the Verify hook of
+// leaderboard servers.
+func VerifySubmission(s *leaderboard.Submission) error {
+	e := fromLeaderboardScore(s.Score)
+	e.Moves = s.Replay
+	if e.Size != PUZZLE_SIZE {
+		return fmt.Errorf("wrong size: %d", e.Size)
+	}
+	if e.Day != "" {
+		seed, err := GetDailySeed(e.Day)
+		if err != nil {
+			return err
+		}
+		if seed != e.Seed {
+			return fmt.Errorf("seed %d is not of the daily puzzle of %s", e.Seed, e.Day)
+		}
+	}
+	if board := boardKey(e.Difficulty, e.Size, e.Day); board != s.Board {
+		return fmt.Errorf("score of board %q is sent to %q", board, s.Board)
+	}
+	return e.replay()
+}
+
+// HasOnline reports whether an online leaderboard is set.
+func (h *HallOfFame) HasOnline() bool { return h.online != nil }
+
+// GetOnlineBoard fetches the online version of the board.
+func (h *HallOfFame) GetOnlineBoard(board *TopScores) (*TopScores, error) {
+	scores, err := h.online.Top(board.GetKey(), MAX_SCORES)
+	if err != nil {
+		return nil, err
+	}
+	b := &TopScores{Difficulty: board.Difficulty, Size: board.Size, Day: board.Day}
+	for _, s := range scores {
+		b.Scores = append(b.Scores, fromLeaderboardScore(s))
+	}
+	return b, nil
+}
+
+// OnlineBoard is an online board fetched in the background.
+type OnlineBoard struct {
+	key   string
+	done  chan struct{}
+	board *TopScores
+	err   error
+}
+
+// FetchOnlineBoard starts fetching the online version of the board.
+func (h *HallOfFame) FetchOnlineBoard(board *TopScores) *OnlineBoard {
+	f := &OnlineBoard{
+		key:  board.GetKey(),
+		done: make(chan struct{}),
+	}
+	go func() {
+		f.board, f.err = h.GetOnlineBoard(board)
+		close(f.done)
+	}()
+	return f
+}
+
+// GetKey returns the key of the fetched board.
+func (f *OnlineBoard) GetKey() string { return f.key }
+
+// Get returns the fetched board. done is false while it is fetched.
+func (f *OnlineBoard) Get() (board *TopScores, done bool, err error) {
+	select {
+	case <-f.done:
+		return f.board, true, f.err
+	default:
+		return nil, false, nil
+	}
+}
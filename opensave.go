@@ -3,19 +3,31 @@ package goeinstein
 import (
 	"bytes"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/veandco/go-sdl2/sdl"
 )
 
 //nolint:golint,nosnakecase,stylecheck
-const MAX_SLOTS = 10
+const (
+	SAVES_PER_PAGE = 8
+
+	SORT_SAVES_BY_DATE = "date"
+	SORT_SAVES_BY_NAME = "name"
+)
 
 type SavedGame struct {
 	fileName string
 	exists   bool
 	name     string
+	info     *SaveInfo
+	modTime  time.Time
+	err      error
 }
 
 func NewSavedGameFile(s string) *SavedGame {
@@ -29,10 +41,29 @@ func NewSavedGameFile(s string) *SavedGame {
 		if os.IsNotExist(err) {
 			return sg
 		}
-		panic(fmt.Errorf("read saved file (filename: %q): %w", sg.fileName, err))
+		sg.exists = true
+		sg.err = fmt.Errorf("read saved file (filename: %q): %w", sg.fileName, err)
+		return sg
 	}
-	sg.name = ReadString(bytes.NewReader(bs))
 	sg.exists = true
+	if st, err := os.Stat(sg.fileName); err == nil {
+		sg.modTime = st.ModTime()
+	}
+	payload, err := ReadSavePayload(bs)
+	if err != nil {
+		sg.err = fmt.Errorf("read saved file (filename: %q): %w", sg.fileName, err)
+		return sg
+	}
+	stream := bytes.NewReader(payload)
+	sg.name, err = ReadString(stream)
+	if err != nil {
+		sg.err = fmt.Errorf("read saved game name (filename: %q): %w", sg.fileName, err)
+		return sg
+	}
+	sg.info, err = NewSaveInfoStream(stream)
+	if err != nil {
+		sg.err = fmt.Errorf("read saved game info (filename: %q): %w", sg.fileName, err)
+	}
 	return sg
 }
 
@@ -40,6 +71,9 @@ func NewSavedGame(s *SavedGame) *SavedGame {
 	sg := &SavedGame{
 		fileName: s.fileName,
 		name:     s.name,
+		info:     s.info,
+		modTime:  s.modTime,
+		err:      s.err,
 	}
 	sg.exists = s.exists
 	return sg
@@ -48,7 +82,23 @@ func NewSavedGame(s *SavedGame) *SavedGame {
 func (s *SavedGame) GetFileName() string { return s.fileName }
 func (s *SavedGame) IsExists() bool      { return s.exists }
 
+func (s *SavedGame) GetInfo() *SaveInfo { return s.info }
+
+// GetTime returns the time the game was saved at.
+func (s *SavedGame) GetTime() time.Time {
+	if s.info != nil && !s.info.Time.IsZero() {
+		return s.info.Time
+	}
+	return s.modTime
+}
+
+// GetError returns the reason why the saved game cannot be loaded.
+func (s *SavedGame) GetError() error { return s.err }
+
 func (s *SavedGame) GetName() string {
+	if s.err != nil {
+		return msg("corruptedSave")
+	}
 	if s.exists {
 		return s.name
 	}
@@ -99,38 +149,33 @@ func NewSaveCommand(sg *SavedGame, f *Font, area *Area, s *bool, dflt string, g
 }
 
 func (s *SaveCommand) DoAction() {
-	area := NewArea()
-	area.AddManaged(s.parentArea, false)
-	area.Add(NewWindow(170, 280, 460, 100, "blue.bmp"))
 	var name string
 	if s.savedGame.IsExists() {
+		if !AskYesNo(s.parentArea, s.font, msg("overwriteSave")) {
+			s.parentArea.UpdateMouse()
+			s.parentArea.Draw()
+			return
+		}
+	}
+	if s.savedGame.IsExists() && s.savedGame.GetError() == nil {
 		name = s.savedGame.GetName()
 	} else {
 		name = s.defaultName
 	}
-	area.Add(NewLabel(s.font, 180, 300, 255, 255, 0, msg("enterGame")))
-	area.Add(NewInputField(340, 300, 280, 26, "blue.bmp", &name, 20, 255, 255, 0, s.font))
-	exitCmd := NewExitCommand(area)
-	okCmd := NewOkCommand(area, s.saved)
-	area.Add(NewButtonText(310, 340, 80, 25, s.font, 255, 255, 0, "blue.bmp", msg("ok"), okCmd))
-	area.Add(NewButtonText(400, 340, 80, 25, s.font, 255, 255, 0, "blue.bmp", msg("cancel"), exitCmd))
-	area.Add(NewKeyAccel(sdl.K_ESCAPE, exitCmd))
-	area.Add(NewKeyAccel(sdl.K_RETURN, okCmd))
-	area.Run()
 
-	if *s.saved {
-		*s.saved = false
-		stream, err := os.OpenFile(s.savedGame.GetFileName(), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
-		if err != nil {
-			ShowMessageWindow(area, "redpattern.bmp", 300, 80, s.font, 255, 255, 255, msg("saveError"))
-			panic(fmt.Errorf("open file to save game (filename: %q): %w", s.savedGame.GetFileName(), err))
+	if AskName(s.parentArea, s.font, msg("enterGame"), &name) {
+		area := s.parentArea
+		var buf bytes.Buffer
+		err := WriteSaveFile(&buf, name, s.game)
+		if err == nil {
+			err = WriteFileAtomic(s.savedGame.GetFileName(), buf.Bytes(), 0o644)
 		}
-		WriteString(stream, name)
-		s.game.Save(stream)
-		err = stream.Close()
 		if err != nil {
+			log.Printf("Error on save game (filename: %q): %v", s.savedGame.GetFileName(), err)
 			ShowMessageWindow(area, "redpattern.bmp", 300, 80, s.font, 255, 255, 255, msg("saveError"))
-			panic(fmt.Errorf("close file to save game (filename: %q): %w", s.savedGame.GetFileName(), err))
+			area.UpdateMouse()
+			area.Draw()
+			return
 		}
 		*s.saved = true
 		s.parentArea.FinishEventLoop()
@@ -140,49 +185,265 @@ func (s *SaveCommand) DoAction() {
 	}
 }
 
+// AskName shows a dialog to enter a name. It returns false if the dialog
+// was cancelled.
+func AskName(parentArea *Area, font *Font, label string, name *string) bool {
+	area := NewArea()
+	area.AddManaged(parentArea, false)
+	area.Add(NewWindow(170, 280, 460, 100, "blue.bmp"))
+	area.Add(NewLabel(font, 180, 300, 255, 255, 0, label))
+	area.Add(NewInputField(340, 300, 280, 26, "blue.bmp", name, 20, 255, 255, 0, font))
+	var ok bool
+	exitCmd := NewExitCommand(area)
+	okCmd := NewOkCommand(area, &ok)
+	area.Add(NewButtonText(310, 340, 80, 25, font, 255, 255, 0, "blue.bmp", msg("ok"), okCmd))
+	area.Add(NewButtonText(400, 340, 80, 25, font, 255, 255, 0, "blue.bmp", msg("cancel"), exitCmd))
+	area.Add(NewKeyAccel(sdl.K_ESCAPE, exitCmd))
+	area.Add(NewKeyAccel(sdl.K_RETURN, okCmd))
+	area.Run()
+	return ok
+}
+
+type RenameCommand struct {
+	savedGame  *SavedGame
+	parentArea *Area
+	font       *Font
+}
+
+var _ Command = (*RenameCommand)(nil)
+
+func NewRenameCommand(sg *SavedGame, f *Font, area *Area) *RenameCommand {
+	return &RenameCommand{
+		savedGame:  sg,
+		parentArea: area,
+		font:       f,
+	}
+}
+
+func (r *RenameCommand) DoAction() {
+	name := r.savedGame.GetName()
+	if AskName(r.parentArea, r.font, msg("enterGame"), &name) {
+		err := RenameSaveFile(r.savedGame.GetFileName(), name)
+		if err != nil {
+			log.Printf("Error on rename game: %v", err)
+			ShowMessageWindow(r.parentArea, "redpattern.bmp", 300, 80, r.font, 255, 255, 255, msg("saveError"))
+		}
+		// the list is rebuilt with the new name
+		r.parentArea.FinishEventLoop()
+		return
+	}
+	r.parentArea.UpdateMouse()
+	r.parentArea.Draw()
+}
+
+type DeleteCommand struct {
+	savedGame  *SavedGame
+	parentArea *Area
+	font       *Font
+}
+
+var _ Command = (*DeleteCommand)(nil)
+
+func NewDeleteCommand(sg *SavedGame, f *Font, area *Area) *DeleteCommand {
+	return &DeleteCommand{
+		savedGame:  sg,
+		parentArea: area,
+		font:       f,
+	}
+}
+
+func (d *DeleteCommand) DoAction() {
+	if AskYesNo(d.parentArea, d.font, msg("deleteSave")) {
+		err := os.Remove(d.savedGame.GetFileName())
+		if err != nil {
+			log.Printf("Error on delete game: %v", err)
+		}
+		// the list is rebuilt without the deleted game
+		d.parentArea.FinishEventLoop()
+		return
+	}
+	d.parentArea.UpdateMouse()
+	d.parentArea.Draw()
+}
+
 func GetSavesPath() string {
-	path := "./einstein/save"
+	path := filepath.Join(GetProfileDataDir(), "save")
 	EnsureDirExists(path)
 	return path
 }
 
-func ShowListWindow(list []*SavedGame, commands []Command, title string, area *Area, font *Font) {
-	titleFont := NewFont("nova.ttf", 26)
+// SaveList is a sorted list of all saved games of a directory.
+type SaveList struct {
+	path   string
+	saves  []*SavedGame
+	sortBy string
+	offset int
+}
 
-	area.Add(NewWindow(250, 90, 300, 420, "blue.bmp"))
-	area.Add(NewLabelAligh(titleFont, 250, 95, 300, 40, ALIGN_CENTER, ALIGN_MIDDLE, 255, 255, 0, title))
-	exitCmd := NewExitCommand(area)
-	area.Add(NewButtonText(360, 470, 80, 25, font, 255, 255, 0, "blue.bmp", msg("close"), exitCmd))
-	area.Add(NewKeyAccel(sdl.K_ESCAPE, exitCmd))
+func NewSaveList(path string) *SaveList {
+	l := &SaveList{
+		path:   path,
+		sortBy: GetStorage().GetString("savesSort", SORT_SAVES_BY_DATE),
+	}
+	l.Reload()
+	return l
+}
 
-	pos := int32(150)
-	no := 0
-	for _, game := range list {
-		area.Add(NewButtonText(260, pos, 280, 25, font, 255, 255, 255, "blue.bmp", game.GetName(), commands[no]))
-		no++
-		pos += 30
+// Reload reads the saved games again, e.g. after a file was deleted.
+func (l *SaveList) Reload() {
+	l.saves = nil
+	names, err := filepath.Glob(filepath.Join(l.path, "*.sav"))
+	if err != nil {
+		panic(fmt.Errorf("list saved games (path: %q): %w", l.path, err))
+	}
+	for _, name := range names {
+		l.saves = append(l.saves, NewSavedGameFile(name))
 	}
+	l.sort()
+	l.Scroll(0)
+}
 
-	area.Run()
+func (l *SaveList) sort() {
+	switch l.sortBy {
+	case SORT_SAVES_BY_NAME:
+		sort.SliceStable(l.saves, func(i, j int) bool {
+			return strings.ToLower(l.saves[i].GetName()) < strings.ToLower(l.saves[j].GetName())
+		})
+	default:
+		sort.SliceStable(l.saves, func(i, j int) bool {
+			return l.saves[i].GetTime().After(l.saves[j].GetTime())
+		})
+	}
+}
+
+func (l *SaveList) Len() int        { return len(l.saves) }
+func (l *SaveList) GetSort() string { return l.sortBy }
+
+func (l *SaveList) SetSort(by string) {
+	l.sortBy = by
+	GetStorage().SetString("savesSort", by)
+	l.sort()
+}
+
+// Scroll moves the visible page by delta games.
+func (l *SaveList) Scroll(delta int) {
+	l.offset += delta
+	if l.offset > len(l.saves)-SAVES_PER_PAGE {
+		l.offset = len(l.saves) - SAVES_PER_PAGE
+	}
+	if l.offset < 0 {
+		l.offset = 0
+	}
+}
+
+// GetPage returns the visible saved games.
+func (l *SaveList) GetPage() []*SavedGame {
+	end := l.offset + SAVES_PER_PAGE
+	if end > len(l.saves) {
+		end = len(l.saves)
+	}
+	return l.saves[l.offset:end]
+}
+
+func (l *SaveList) CanScrollUp() bool   { return l.offset > 0 }
+func (l *SaveList) CanScrollDown() bool { return l.offset+SAVES_PER_PAGE < len(l.saves) }
+
+// NewFileName returns a name of a file for a new saved game.
+func (l *SaveList) NewFileName() string {
+	for i := 0; ; i++ {
+		name := filepath.Join(l.path, ToString(i)+".sav")
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			return name
+		}
+	}
+}
+
+// ShowListWindow shows the saved games. command returns the command of
+// a click on a saved game. If newCommand is not nil, a button with newText
+// is shown, e.g. to create a new saved game. The window is closed when
+// a command finishes the event loop of the area and done returns true.
+func ShowListWindow(list *SaveList, title string, parentArea *Area, font *Font,
+	command func(sg *SavedGame, area *Area) Command, newText string, newCommand func(area *Area) Command, done func() bool) {
+	titleFont := NewFont("nova.ttf", 26)
+
+	for {
+		area := NewArea()
+		area.AddManaged(parentArea, false)
+
+		var closed bool
+		rebuild := func(fn func()) Command {
+			return FnCommand(func() {
+				fn()
+				area.FinishEventLoop()
+			})
+		}
+
+		area.Add(NewWindow(110, 90, 580, 420, "blue.bmp"))
+		area.Add(NewLabelAligh(titleFont, 110, 95, 580, 40, ALIGN_CENTER, ALIGN_MIDDLE, 255, 255, 0, title))
+
+		if newCommand != nil {
+			area.Add(NewButtonText(120, 140, 140, 25, font, 255, 255, 0, "blue.bmp", newText, newCommand(area)))
+		}
+		sortText, nextSort := msg("sortByDate"), SORT_SAVES_BY_NAME
+		if list.GetSort() == SORT_SAVES_BY_NAME {
+			sortText, nextSort = msg("sortByName"), SORT_SAVES_BY_DATE
+		}
+		area.Add(NewButtonText(270, 140, 140, 25, font, 255, 255, 0, "blue.bmp", sortText, rebuild(func() { list.SetSort(nextSort) })))
+
+		page := list.GetPage()
+		pos := int32(175)
+		for _, sg := range page {
+			area.Add(NewButtonText(120, pos, 170, 25, font, 255, 255, 255, "blue.bmp", sg.GetName(), command(sg, area)))
+			area.Add(NewButtonText(295, pos, 55, 25, font, 255, 255, 0, "blue.bmp", msg("rename"), NewRenameCommand(sg, font, area)))
+			area.Add(NewButtonText(355, pos, 55, 25, font, 255, 255, 0, "blue.bmp", msg("delete"), NewDeleteCommand(sg, font, area)))
+			pos += 30
+		}
+		if len(page) == 0 {
+			area.Add(NewLabelAligh(font, 120, 175, 290, 25, ALIGN_CENTER, ALIGN_MIDDLE, 255, 255, 255, msg("noSaves")))
+		}
+		area.Add(NewSaveInfoPanel(420, 140, 260, 310, font, page, 120, 175, 170, 30))
+
+		upCmd := rebuild(func() { list.Scroll(-SAVES_PER_PAGE) })
+		downCmd := rebuild(func() { list.Scroll(SAVES_PER_PAGE) })
+		if list.CanScrollUp() {
+			area.Add(NewButtonText(120, 425, 80, 25, font, 255, 255, 0, "blue.bmp", msg("prev"), upCmd))
+		}
+		if list.CanScrollDown() {
+			area.Add(NewButtonText(330, 425, 80, 25, font, 255, 255, 0, "blue.bmp", msg("next"), downCmd))
+		}
+		area.Add(NewKeyAccel(sdl.K_PAGEUP, upCmd))
+		area.Add(NewKeyAccel(sdl.K_PAGEDOWN, downCmd))
+
+		exitCmd := rebuild(func() { closed = true })
+		area.Add(NewButtonText(360, 470, 80, 25, font, 255, 255, 0, "blue.bmp", msg("close"), exitCmd))
+		area.Add(NewKeyAccel(sdl.K_ESCAPE, exitCmd))
+
+		area.Run()
+		area.Close()
+
+		if closed || done() || IsQuitRequested() {
+			return
+		}
+		list.Reload()
+	}
 }
 
 func SaveGame(parentArea *Area, game *Game) bool {
-	path := GetSavesPath()
+	list := NewSaveList(GetSavesPath())
 
 	area := NewArea()
 	area.AddManaged(parentArea, false)
 	font := NewFont("laudcn2.ttf", 14)
 	saved := false
 
-	var list []*SavedGame
-	commands := make([]Command, MAX_SLOTS)
-	for i := 0; i < MAX_SLOTS; i++ {
-		sg := NewSavedGameFile(path + "/" + ToString(i) + ".sav")
-		list = append(list, sg)
-		commands[i] = NewSaveCommand(sg, font, area, &saved, "game "+ToString(i+1), game)
+	command := func(sg *SavedGame, area *Area) Command {
+		return NewSaveCommand(sg, font, area, &saved, sg.GetName(), game)
 	}
-
-	ShowListWindow(list, commands, msg("saveGame"), area, font)
+	newCommand := func(area *Area) Command {
+		sg := NewSavedGameFile(list.NewFileName())
+		return NewSaveCommand(sg, font, area, &saved, "game "+ToString(list.Len()+1), game)
+	}
+	ShowListWindow(list, msg("saveGame"), area, font, command, msg("newSave"), newCommand, func() bool { return saved })
 
 	return saved
 }
@@ -209,20 +470,60 @@ func NewLoadCommand(sg *SavedGame, f *Font, area *Area, g **Game) *LoadCommand {
 }
 
 func (l *LoadCommand) DoAction() {
-	bs, err := os.ReadFile(l.savedGame.GetFileName())
+	g, err := LoadSavedGame(l.savedGame.GetFileName())
 	if err != nil {
-		panic(fmt.Errorf("read all file (filename: %q): %w", l.savedGame.GetFileName(), err))
+		log.Printf("Error on load game: %v", err)
+		ShowMessageWindow(l.parentArea, "redpattern.bmp", 300, 80, l.font, 255, 255, 255, msg("corruptedSave"))
+		l.parentArea.UpdateMouse()
+		l.parentArea.Draw()
+		return
 	}
-	stream := bytes.NewReader(bs)
-	ReadString(stream)
-	g := NewGameStream(stream)
 	*l.game = g
 
 	l.parentArea.FinishEventLoop()
 }
 
+// LoadSavedGame reads the game saved to the file.
+func LoadSavedGame(fileName string) (*Game, error) {
+	g, _, err := loadSavedGame(fileName)
+	return g, err
+}
+
+func loadSavedGame(fileName string) (*Game, *SaveInfo, error) {
+	bs, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read all file (filename: %q): %w", fileName, err)
+	}
+	payload, err := ReadSavePayload(bs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read saved file (filename: %q): %w", fileName, err)
+	}
+	stream := bytes.NewReader(payload)
+	_, err = ReadString(stream)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read saved game name (filename: %q): %w", fileName, err)
+	}
+	info, err := NewSaveInfoStream(stream)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read saved game info (filename: %q): %w", fileName, err)
+	}
+	g, err := NewGameStream(stream)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load game (filename: %q): %w", fileName, err)
+	}
+	g.difficulty = info.Difficulty
+	g.seed = info.Seed
+	g.daily, _ = GetDailyDayOfSeed(info.Seed)
+	g.started = info.Started
+	if g.started.IsZero() {
+		g.started = time.Now()
+	}
+	ResumeGame(g)
+	return g, info, nil
+}
+
 func LoadGame(parentArea *Area) *Game {
-	path := GetSavesPath()
+	list := NewSaveList(GetSavesPath())
 
 	area := NewArea()
 	area.AddManaged(parentArea, false)
@@ -230,19 +531,16 @@ func LoadGame(parentArea *Area) *Game {
 
 	var newGame *Game
 
-	var list []*SavedGame
-	var commands [MAX_SLOTS]Command
-	for i := 0; i < MAX_SLOTS; i++ {
-		sg := NewSavedGameFile(filepath.Join(path, ToString(i)+".sav"))
-		list = append(list, sg)
-		if sg.IsExists() {
-			commands[i] = NewLoadCommand(list[i], font, area, &newGame)
-		} else {
-			commands[i] = nil
+	command := func(sg *SavedGame, area *Area) Command {
+		if sg.GetError() != nil {
+			return nil
 		}
+		return NewLoadCommand(sg, font, area, &newGame)
 	}
-
-	ShowListWindow(list, commands[:], msg("loadGame"), area, font)
+	importCommand := func(area *Area) Command {
+		return NewImportCommand(list, font, area)
+	}
+	ShowListWindow(list, msg("loadGame"), area, font, command, msg("import"), importCommand, func() bool { return newGame != nil })
 
 	return newGame
 }
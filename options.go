@@ -4,140 +4,103 @@ import (
 	"github.com/veandco/go-sdl2/sdl"
 )
 
-var options = struct {
-	Fullscreen *BoolConfig
-	NiceCursor *BoolConfig
-	AutoHints  *BoolConfig
-
-	HighlightHints *BoolConfig
-}{
-	Fullscreen: NewBoolConfigCmd("fullscreen", false, screen.SetFullscreen),
-	NiceCursor: NewBoolConfigCmd("niceCursor", true, screen.SetCursor),
-	AutoHints: NewBoolConfigCmd("autoHints", false, func(b bool) {
-		if b {
-			game.SetHinted()
-		}
-	}),
-	HighlightHints: NewBoolConfig("highlightHints", false),
+// Options are the settings of the options window. A new setting is declared
+// here and registered in NewOptions, the window is generated from the
+// registry.
+type Options struct {
+	Settings *Settings
+
+	Fullscreen *BoolSetting
+	NiceCursor *BoolSetting
+	AutoHints  *BoolSetting
+
+	HighlightHints *BoolSetting
+	Volume         *IntSetting
+	// OnlineServer is the URL of the online leaderboard, "" if it is off.
+	OnlineServer *StringSetting
 }
 
-type OptionsChangedCommand struct {
-	volume    *float32
-	oldVolume float32
-}
-
-var _ Command = (*OptionsChangedCommand)(nil)
-
-func NewOptionsChangedCommand(v *float32) *OptionsChangedCommand {
-	o := &OptionsChangedCommand{
-		volume:    v,
-		oldVolume: *v,
+// options are read from the storage in Main, as the storage location depends
+// on the command line.
+var options *Options
+
+func NewOptions() *Options {
+	setVolume := func(v int) { sound.SetVolume(float32(v) / 100.0) }
+	o := &Options{
+		Settings: NewSettings(),
+
+		Fullscreen: NewBoolSetting("screen.fullscreen", "fullscreen", false, screen.SetFullscreen),
+		NiceCursor: NewBoolSetting("screen.niceCursor", "niceCursor", true, screen.SetCursor),
+		AutoHints: NewBoolSetting("game.autoHints", "autoHints", false, func(b bool) {
+			if b && game != nil {
+				game.SetHinted()
+			}
+		}),
+		HighlightHints: NewBoolSetting("game.highlightHints", "highlightHints", false, nil),
+		Volume:         NewIntSetting("sound.volume", "volume", 0, 100, 20, setVolume, setVolume),
+		OnlineServer:   NewStringSetting("online.server", "onlineServer", 100, "", nil),
 	}
+	o.Settings.AddLegacy(o.Fullscreen, "fullscreen")
+	o.Settings.AddLegacy(o.NiceCursor, "niceCursor")
+	o.Settings.AddLegacy(o.AutoHints, "autoHints")
+	o.Settings.AddLegacy(o.HighlightHints, "highlightHints")
+	o.Settings.AddLegacy(o.Volume, "volume")
+	o.Settings.Add(o.OnlineServer)
+
+	o.Settings.Load(GetStorage())
 	return o
 }
 
-func (o *OptionsChangedCommand) DoAction() {
-	if *o.volume != o.oldVolume {
-		GetStorage().SetInt("volume", int(*o.volume*100.0))
-		sound.SetVolume(*o.volume)
-	}
-	GetStorage().Flush()
+// ReloadOptions reads the options of the current profile and applies them.
+func ReloadOptions() {
+	options = NewOptions()
+	screen.SetFullscreen(options.Fullscreen.Value())
+	screen.SetCursor(options.NiceCursor.Value())
+	sound.SetVolume(float32(options.Volume.Value()) / 100.0)
 }
 
 func ShowOptionsWindow(parentArea *Area) {
 	titleFont := NewFont("nova.ttf", 26)
 	font := NewFont("laudcn2.ttf", 14)
 
-	volume := sound.volume
+	const rowHeight = 25
+	settings := options.Settings.GetAll()
+	width := int32(300)
+	height := int32(120 + len(settings)*rowHeight)
+	x := (screen.GetWidth() - width) / 2
+	y := (screen.GetHeight() - height) / 2
 
 	area := NewArea()
 
-	LABEL := func(y int32, s string) {
-		area.Add(NewLabelAligh(font, 300, y, 300, 20, ALIGN_LEFT, ALIGN_MIDDLE, 255, 255, 255, msg(s)))
-	}
-	CHECKBOX := func(y int32, v *bool) {
-		area.Add(NewCheckbox(265, y, 20, 20, font, 255, 255, 255, "blue.bmp", v))
-	}
-	OPTION := func(y int32, s string, v *bool) {
-		LABEL(y, s)
-		CHECKBOX(y, v)
-	}
-
 	area.Add(parentArea)
-	area.Add(NewWindow(250, 170, 300, 260, "blue.bmp"))
-	area.Add(NewLabelAligh(titleFont, 250, 175, 300, 40, ALIGN_CENTER, ALIGN_MIDDLE, 255, 255, 0, msg("options")))
-
-	x := int32(240)
-	var checkboxCommands Commands
-	for _, ch := range []*BoolConfig{
-		options.Fullscreen,
-		options.NiceCursor,
-		options.AutoHints,
-		options.HighlightHints,
-	} {
-		OPTION(x, ch.name, &ch.value)
-		checkboxCommands = append(checkboxCommands, ch)
-		x += 20
+	area.Add(NewWindow(x, y, width, height, "blue.bmp"))
+	area.Add(NewLabelAligh(titleFont, x, y+5, width, 40, ALIGN_CENTER, ALIGN_MIDDLE, 255, 255, 0, msg("options")))
+
+	rowY := y + 60
+	for _, s := range settings {
+		area.Add(NewLabelAligh(font, x+15, rowY, 130, 20, ALIGN_LEFT, ALIGN_MIDDLE, 255, 255, 255, msg(s.GetTitle())))
+		s.AddEditor(area, font, x+150, rowY, width-165, 20)
+		rowY += rowHeight
 	}
 
-	area.Add(NewLabelAligh(font, 265, 330, 300, 20, ALIGN_LEFT, ALIGN_MIDDLE, 255, 255, 255, msg("volume")))
-	area.Add(NewSlider(360, 332, 160, 16, &volume))
-
-	exitCmd := area.FinishCommand()
-	okCmd := Combine(
-		checkboxCommands,
-		NewOptionsChangedCommand(&volume),
-		exitCmd,
-	)
-	area.Add(NewButtonText(315, 390, 85, 25, font, 255, 255, 0, "blue.bmp", msg("ok"), okCmd))
-	area.Add(NewButtonText(405, 390, 85, 25, font, 255, 255, 0, "blue.bmp", msg("cancel"), exitCmd))
-	area.Add(NewKeyAccel(sdl.K_ESCAPE, exitCmd))
+	var ok bool
+	cancelCmd := area.FinishCommand()
+	okCmd := NewOkCommand(area, &ok)
+	area.Add(NewButtonText(x+width/2-90, y+height-45, 85, 25, font, 255, 255, 0, "blue.bmp", msg("ok"), okCmd))
+	area.Add(NewButtonText(x+width/2+5, y+height-45, 85, 25, font, 255, 255, 0, "blue.bmp", msg("cancel"), cancelCmd))
+	area.Add(NewKeyAccel(sdl.K_ESCAPE, cancelCmd))
 	area.Add(NewKeyAccel(sdl.K_RETURN, okCmd))
 	area.Run()
-}
 
-type BoolConfig struct {
-	name     string
-	value    bool
-	oldValue bool
-	onSaveFn BoolConfigOnSaveFunc
+	// edits are reverted also if the window is closed by a quit request
+	if ok {
+		options.Settings.Apply(GetStorage())
+	} else {
+		options.Settings.Revert()
+	}
 }
 
-type BoolConfigOnSaveFunc func(bool)
-
 var boolToInt = map[bool]int{
 	false: 0,
 	true:  1,
 }
-
-func NewBoolConfigCmd(name string, v bool, onSave BoolConfigOnSaveFunc) *BoolConfig {
-	v = GetStorage().GetInt(name, boolToInt[v]) > 0
-	return &BoolConfig{
-		name:     name,
-		value:    v,
-		oldValue: v,
-		onSaveFn: onSave,
-	}
-}
-
-func NewBoolConfig(name string, v bool) *BoolConfig {
-	return NewBoolConfigCmd(name, v, nil)
-}
-
-func (b *BoolConfig) Value() bool { return b.value }
-
-func (b *BoolConfig) Save() {
-	if b.value == b.oldValue {
-		return
-	}
-
-	b.oldValue = b.value
-	GetStorage().SetInt(b.name, boolToInt[b.value])
-	if b.onSaveFn != nil {
-		b.onSaveFn(b.value)
-	}
-}
-
-func (b *BoolConfig) DoAction() {
-	b.Save()
-}
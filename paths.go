@@ -0,0 +1,176 @@
+package goeinstein
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// LEGACY_DIR is the directory used before the config and data directories
+// were split. It is still used in the portable mode.
+//
+//nolint:golint,nosnakecase,stylecheck
+const LEGACY_DIR = "./einstein"
+
+var portable bool
+
+// SetPortable keeps all files in LEGACY_DIR of the working directory. It must
+// be called before the storage is used.
+func SetPortable(v bool) { portable = v }
+
+func IsPortable() bool { return portable }
+
+// GetConfigDir returns the directory of the settings and crash reports:
+// $XDG_CONFIG_HOME/einstein or its platform equivalent. The directory is
+// created if needed.
+func GetConfigDir() string {
+	path := configDir()
+	EnsureDirExists(path)
+	return path
+}
+
+// GetDataDir returns the directory of saved games: $XDG_DATA_HOME/einstein or
+// its platform equivalent. The directory is created if needed.
+func GetDataDir() string {
+	path := dataDir()
+	EnsureDirExists(path)
+	return path
+}
+
+func configDir() string {
+	if portable {
+		return LEGACY_DIR
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		log.Printf("Error on get config dir, %q is used: %v", LEGACY_DIR, err)
+		return LEGACY_DIR
+	}
+	return filepath.Join(dir, "einstein")
+}
+
+func dataDir() string {
+	if portable {
+		return LEGACY_DIR
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "einstein")
+	}
+	switch runtime.GOOS {
+	case "windows", "darwin", "ios", "plan9":
+		// there is no separate data directory
+		return configDir()
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Printf("Error on get data dir, %q is used: %v", LEGACY_DIR, err)
+		return LEGACY_DIR
+	}
+	return filepath.Join(home, ".local", "share", "einstein")
+}
+
+// MigrateLegacyDir moves files of LEGACY_DIR to the config and data
+// directories on the first run. Files which already exist there are left in
+// place. Errors are only logged: the game starts with defaults then.
+func MigrateLegacyDir() {
+	if portable {
+		return
+	}
+	if info, err := os.Stat(LEGACY_DIR); err != nil || !info.IsDir() {
+		return
+	}
+	if _, err := os.Stat(configDir()); err == nil {
+		return
+	}
+
+	entries, err := os.ReadDir(LEGACY_DIR)
+	if err != nil {
+		log.Printf("Error on migrate %q: %v", LEGACY_DIR, err)
+		return
+	}
+	configPath, dataPath := GetConfigDir(), GetDataDir()
+	for _, e := range entries {
+		dst := dataPath
+		if isConfigFile(e.Name()) {
+			dst = configPath
+		}
+		src := filepath.Join(LEGACY_DIR, e.Name())
+		dst = filepath.Join(dst, e.Name())
+		if _, err := os.Stat(dst); err == nil {
+			continue
+		}
+		err = moveFile(src, dst)
+		if err != nil {
+			log.Printf("Error on migrate %q: %v", src, err)
+		}
+	}
+	// only removed if everything was moved
+	_ = os.Remove(LEGACY_DIR)
+	log.Printf("Files of %q are moved to %q and %q", LEGACY_DIR, configPath, dataPath)
+}
+
+func isConfigFile(name string) bool {
+	return name == "conf.cfg" || strings.HasPrefix(name, "crash-") && strings.HasSuffix(name, ".txt")
+}
+
+// moveFile renames the file or directory, copying it if it is moved to
+// another file system.
+func moveFile(src, dst string) error {
+	if os.Rename(src, dst) == nil {
+		return nil
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		err = os.MkdirAll(dst, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			err = moveFile(filepath.Join(src, e.Name()), filepath.Join(dst, e.Name()))
+			if err != nil {
+				return err
+			}
+		}
+		return os.Remove(src)
+	}
+
+	err = copyFile(src, dst, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("copy %q to %q: %w", src, dst, err)
+	}
+	return nil
+}
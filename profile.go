@@ -0,0 +1,256 @@
+package goeinstein
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+//nolint:golint,nosnakecase,stylecheck
+const (
+	// DEFAULT_PROFILE keeps its files in the config and data directories
+	// themselves, as before profiles were added.
+	DEFAULT_PROFILE = "default"
+	MAX_PROFILES    = 8
+)
+
+// Profile is a local player. Options, saved games and the history are kept
+// per profile, the hall of fame is shared.
+type Profile struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// GetName returns the name of the profile, the default profile may have none.
+func (p *Profile) GetName() string {
+	if p.Name == "" {
+		return msg("defaultProfile")
+	}
+	return p.Name
+}
+
+type ProfileList struct {
+	Current  string     `json:"current"`
+	Profiles []*Profile `json:"profiles"`
+}
+
+var profiles *ProfileList
+
+func GetProfilesFileName() string {
+	return filepath.Join(GetConfigDir(), "profiles.json")
+}
+
+// GetProfiles returns the profiles, they are read on the first use.
+func GetProfiles() *ProfileList {
+	if profiles == nil {
+		profiles = loadProfiles()
+	}
+	return profiles
+}
+
+func loadProfiles() *ProfileList {
+	l := &ProfileList{}
+	bs, err := os.ReadFile(GetProfilesFileName())
+	if err == nil {
+		err = json.Unmarshal(bs, l)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("Error on read profiles (filename: %q): %v", GetProfilesFileName(), err)
+		l = &ProfileList{}
+	}
+	if l.Get(DEFAULT_PROFILE) == nil {
+		l.Profiles = append([]*Profile{{ID: DEFAULT_PROFILE}}, l.Profiles...)
+	}
+	if l.Get(l.Current) == nil {
+		l.Current = DEFAULT_PROFILE
+	}
+	return l
+}
+
+func (l *ProfileList) Save() error {
+	bs, err := json.MarshalIndent(l, "", "\t")
+	if err != nil {
+		return fmt.Errorf("marshal profiles: %w", err)
+	}
+	return WriteFileAtomic(GetProfilesFileName(), bs, 0o664)
+}
+
+func (l *ProfileList) Get(id string) *Profile {
+	for _, p := range l.Profiles {
+		if p.ID == id {
+			return p
+		}
+	}
+	return nil
+}
+
+func (l *ProfileList) GetCurrent() *Profile { return l.Get(l.Current) }
+
+func (l *ProfileList) CanAdd() bool { return len(l.Profiles) < MAX_PROFILES }
+
+// Add creates a profile. The files of the profile are created on the first
+// use.
+func (l *ProfileList) Add(name string) *Profile {
+	var last int
+	for _, p := range l.Profiles {
+		if n, err := strconv.Atoi(strings.TrimPrefix(p.ID, "p")); err == nil && n > last {
+			last = n
+		}
+	}
+	p := &Profile{ID: "p" + strconv.Itoa(last+1), Name: name}
+	l.Profiles = append(l.Profiles, p)
+	return p
+}
+
+// Remove deletes the profile with its options, saves and history. The default
+// profile cannot be removed.
+func (l *ProfileList) Remove(id string) error {
+	if id == DEFAULT_PROFILE {
+		return fmt.Errorf("default profile cannot be removed")
+	}
+	for i, p := range l.Profiles {
+		if p.ID != id {
+			continue
+		}
+		if id == l.Current {
+			SwitchProfile(DEFAULT_PROFILE)
+		}
+		l.Profiles = append(l.Profiles[:i], l.Profiles[i+1:]...)
+		for _, base := range []string{GetConfigDir(), GetDataDir()} {
+			if err := os.RemoveAll(profileDir(base, id)); err != nil {
+				return fmt.Errorf("remove profile %q: %w", id, err)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("profile %q not found", id)
+}
+
+func profileDir(base, id string) string {
+	if id == DEFAULT_PROFILE {
+		return base
+	}
+	return filepath.Join(base, "profiles", id)
+}
+
+// GetProfileConfigDir returns the directory of the options of the current
+// profile. The directory is created if needed.
+func GetProfileConfigDir() string {
+	path := profileDir(GetConfigDir(), GetProfiles().Current)
+	EnsureDirExists(path)
+	return path
+}
+
+// GetProfileDataDir returns the directory of saved games and the history of
+// the current profile. The directory is created if needed.
+func GetProfileDataDir() string {
+	path := profileDir(GetDataDir(), GetProfiles().Current)
+	EnsureDirExists(path)
+	return path
+}
+
+// SwitchProfile makes the profile current: the storage and the options are
+// read again and applied to the screen and sound.
+func SwitchProfile(id string) {
+	l := GetProfiles()
+	if l.Get(id) == nil || id == l.Current {
+		return
+	}
+	ResetStorage()
+	l.Current = id
+	if err := l.Save(); err != nil {
+		log.Printf("Error on save profiles: %v", err)
+	}
+	ReloadOptions()
+}
+
+// ShowProfilesWindow lets the player choose, create, rename and remove
+// profiles.
+func ShowProfilesWindow(parentArea *Area) {
+	titleFont := NewFont("nova.ttf", 26)
+	font := NewFont("laudcn2.ttf", 14)
+	l := GetProfiles()
+
+	for {
+		area := NewArea()
+		area.AddManaged(parentArea, false)
+
+		var closed bool
+		rebuild := func(fn func()) Command {
+			return FnCommand(func() {
+				fn()
+				area.FinishEventLoop()
+			})
+		}
+		save := func() {
+			if err := l.Save(); err != nil {
+				log.Printf("Error on save profiles: %v", err)
+			}
+		}
+
+		area.Add(NewWindow(200, 90, 400, 420, "blue.bmp"))
+		area.Add(NewLabelAligh(titleFont, 200, 95, 400, 40, ALIGN_CENTER, ALIGN_MIDDLE, 255, 255, 0, msg("profiles")))
+
+		pos := int32(145)
+		for _, p := range l.Profiles {
+			p := p
+			var c uint8 = 255
+			if p.ID == l.Current {
+				c = 0
+			}
+			selectCmd := rebuild(func() {
+				SwitchProfile(p.ID)
+				closed = true
+			})
+			area.Add(NewButtonText(215, pos, 210, 25, font, 255, 255, c, "blue.bmp", p.GetName(), selectCmd))
+			renameCmd := rebuild(func() {
+				name := p.GetName()
+				if AskName(area, font, msg("profileName"), &name) && name != "" {
+					p.Name = name
+					save()
+				}
+			})
+			area.Add(NewButtonText(430, pos, 75, 25, font, 255, 255, 0, "blue.bmp", msg("rename"), renameCmd))
+			if p.ID != DEFAULT_PROFILE {
+				deleteCmd := rebuild(func() {
+					if !AskYesNo(area, font, msg("deleteProfile")) {
+						return
+					}
+					if err := l.Remove(p.ID); err != nil {
+						log.Printf("Error on delete profile: %v", err)
+					}
+					save()
+				})
+				area.Add(NewButtonText(510, pos, 75, 25, font, 255, 255, 0, "blue.bmp", msg("delete"), deleteCmd))
+			}
+			pos += 30
+		}
+
+		if l.CanAdd() {
+			newCmd := rebuild(func() {
+				name := msg("player") + " " + ToString(len(l.Profiles)+1)
+				if AskName(area, font, msg("profileName"), &name) && name != "" {
+					l.Add(name)
+					save()
+				}
+			})
+			area.Add(NewButtonText(215, 470, 120, 25, font, 255, 255, 0, "blue.bmp", msg("newProfile"), newCmd))
+		}
+		exitCmd := rebuild(func() { closed = true })
+		area.Add(NewButtonText(465, 470, 120, 25, font, 255, 255, 0, "blue.bmp", msg("close"), exitCmd))
+		area.Add(NewKeyAccel(sdl.K_ESCAPE, exitCmd))
+
+		area.Run()
+		area.Close()
+
+		if closed || IsQuitRequested() {
+			return
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package goeinstein
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
@@ -8,7 +9,12 @@ import (
 )
 
 //nolint:golint,nosnakecase,stylecheck
-const PUZZLE_SIZE = 6
+const (
+	PUZZLE_SIZE = 6
+	// MAX_LAYOUT_ATTEMPTS limits the puzzles generated until the hints fit
+	// on the screen.
+	MAX_LAYOUT_ATTEMPTS = 100
+)
 
 type SolvedPuzzle [PUZZLE_SIZE][PUZZLE_SIZE]Card
 
@@ -22,14 +28,17 @@ func NewPossibilities() *Possibilities {
 	return p
 }
 
-func NewPossibilitiesStream(stream io.Reader) *Possibilities {
+func NewPossibilitiesStream(stream io.Reader) (*Possibilities, error) {
 	p := &Possibilities{}
 	for row := 0; row < PUZZLE_SIZE; row++ {
 		for col := 0; col < PUZZLE_SIZE; col++ {
-			p.pos[col][row].ReadFrom(stream)
+			err := p.pos[col][row].ReadFrom(stream)
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
-	return p
+	return p, nil
 }
 
 func (p *Possibilities) Reset() {
@@ -256,40 +265,251 @@ func RemoveRules(puzzle *SolvedPuzzle, rules *Rules) {
 	}
 }
 
+// RemoveRulesMinimal searches over removal orders for the smallest rule set
+// that still solves the puzzle. The search is limited to limit solver runs;
+// the best set found so far is kept when the limit is reached.
+func RemoveRulesMinimal(puzzle *SolvedPuzzle, rules *Rules, limit int) {
+	best := append(Rules{}, (*rules)...)
+	RemoveRules(puzzle, &best)
+
+	budget := limit
+	var search func(current Rules, from int)
+	search = func(current Rules, from int) {
+		// rules before from are kept, so a set smaller than best can only
+		// be found while ri < len(best)
+		for ri := from; ri < len(current) && ri < len(best) && budget > 0; ri++ {
+			excludedRules := append(append(Rules{}, current[:ri]...), current[ri+1:]...)
+			budget--
+			if CanSolve(puzzle, &excludedRules) {
+				if len(excludedRules) < len(best) {
+					best = excludedRules
+				}
+				search(excludedRules, ri)
+			}
+		}
+	}
+	search(*rules, 0)
+
+	*rules = best
+}
+
+// RedundantRules returns indexes of rules which can be removed one at a time
+// without making the puzzle unsolvable.
+func RedundantRules(puzzle *SolvedPuzzle, rules *Rules) []int {
+	var out []int
+	for ri := range *rules {
+		excludedRules := append(append(Rules{}, (*rules)[:ri]...), (*rules)[ri+1:]...)
+		if CanSolve(puzzle, &excludedRules) {
+			out = append(out, ri)
+		}
+	}
+	return out
+}
+
 func GenRules(puzzle *SolvedPuzzle, rules *Rules, rand *rand.Rand) {
-	var rulesDone bool
+	GenRulesFilter(puzzle, rules, rand, NewRuleWeights(), nil)
+}
 
-	for {
-		rule := GenRule(puzzle, rand)
+// GenRulesFilter adds random rules until the puzzle becomes solvable.
+// Rules rejected by filter are skipped; without filter only rules with
+// the same text are skipped. It returns false if the puzzle is still not
+// solvable after MAX_GEN_RULE_DRAWS generated rules.
+func GenRulesFilter(puzzle *SolvedPuzzle, rules *Rules, rand *rand.Rand, weights RuleWeights, filter *QualityFilter) bool {
+	for i := 0; i < MAX_GEN_RULE_DRAWS; i++ {
+		rule := GenRuleWeights(puzzle, rand, weights)
 		if rule != nil {
-			s := rule.GetAsText()
-			for _, r := range *rules {
-				if r.GetAsText() == s {
+			if filter != nil {
+				if !filter.Accept(*rules, rule) {
 					rule = nil
-					break
+				}
+			} else {
+				s := rule.GetAsText()
+				for _, r := range *rules {
+					if r.GetAsText() == s {
+						rule = nil
+						break
+					}
 				}
 			}
 			if rule != nil {
 				*rules = append(*rules, rule)
-				rulesDone = CanSolve(puzzle, rules)
+				if CanSolve(puzzle, rules) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+//nolint:golint,nosnakecase,stylecheck
+const (
+	DEFAULT_MINIMAL_SEARCH_LIMIT = 2000
+	DEFAULT_QUALITY_ATTEMPTS     = 20
+	MAX_GEN_RULE_DRAWS           = 10000
+)
+
+// GenConfig holds the puzzle generator settings.
+type GenConfig struct {
+	// Weights sets how often rules of each type are generated.
+	Weights RuleWeights
+	// Redundancy is the number of redundant rules kept in the final set.
+	Redundancy int
+	// Minimal searches over removal orders for the smallest rule set instead
+	// of greedily removing the first removable rule.
+	Minimal bool
+	// MinimalSearchLimit bounds the number of solver runs of the minimal search.
+	MinimalSearchLimit int
+	// Quality filters generated rules; nil disables the quality checks.
+	Quality *QualityConfig
+	// QualityAttempts is how many rule sets may be rejected as unbalanced
+	// before the last one is accepted anyway.
+	QualityAttempts int
+	// QualityLog receives the reasons of rejections, may be nil.
+	QualityLog *QualityLog
+}
+
+func NewGenConfig() *GenConfig {
+	return &GenConfig{
+		Weights:            NewRuleWeights(),
+		MinimalSearchLimit: DEFAULT_MINIMAL_SEARCH_LIMIT,
+		QualityAttempts:    DEFAULT_QUALITY_ATTEMPTS,
+	}
+}
+
+func (c *GenConfig) Clone() *GenConfig {
+	out := *c
+	out.Weights = c.Weights.Clone()
+	return &out
+}
+
+func GenPuzzle(puzzle *SolvedPuzzle, rules *Rules, rand *rand.Rand) {
+	_, err := GenPuzzleConfig(puzzle, rules, rand, NewGenConfig())
+	if err != nil {
+		panic(fmt.Errorf("generate puzzle: %w", err))
+	}
+}
+
+// GenPuzzleConfig generates a puzzle according to cfg and returns indexes of
+// the rules which are redundant in the final set.
+func GenPuzzleConfig(puzzle *SolvedPuzzle, rules *Rules, rand *rand.Rand, cfg *GenConfig) ([]int, error) {
+	if cfg.Weights.Total() <= 0 {
+		return nil, fmt.Errorf("all rule types are disabled")
+	}
+
+	var filter *QualityFilter
+	if cfg.Quality != nil {
+		filter = NewQualityFilter(cfg.Quality, cfg.QualityLog)
+	}
+
+	for attempt := 0; ; attempt++ {
+		for i := 0; i < PUZZLE_SIZE; i++ {
+			for j := 0; j < PUZZLE_SIZE; j++ {
+				puzzle[i][j] = Card(j + 1)
 			}
+			Shuffle(&(*puzzle)[i], rand)
+		}
+
+		*rules = nil
+		if !GenRulesFilter(puzzle, rules, rand, cfg.Weights, filter) {
+			return nil, fmt.Errorf("puzzle is not solvable with enabled rule types")
 		}
-		if rulesDone {
+		allRules := append(Rules{}, (*rules)...)
+		if cfg.Minimal {
+			RemoveRulesMinimal(puzzle, rules, cfg.MinimalSearchLimit)
+		} else {
+			RemoveRules(puzzle, rules)
+		}
+		AddRedundantRules(rules, allRules, cfg.Redundancy, rand)
+
+		if filter == nil || filter.Check(*rules) || attempt+1 >= cfg.QualityAttempts {
 			break
 		}
+		// every rule of the failed attempt is in allRules
+		allRules.Close()
 	}
+
+	return RedundantRules(puzzle, rules), nil
 }
 
-func GenPuzzle(puzzle *SolvedPuzzle, rules *Rules, rand *rand.Rand) {
-	for i := 0; i < PUZZLE_SIZE; i++ {
-		for j := 0; j < PUZZLE_SIZE; j++ {
-			puzzle[i][j] = Card(j + 1)
+// GenGamePuzzle generates the puzzle of a game: puzzles with more hints than
+// fit on the screen are skipped. It fails if no puzzle fits in
+// MAX_LAYOUT_ATTEMPTS attempts, e.g. with weights of rule types which always
+// give too many hints.
+func GenGamePuzzle(puzzle *SolvedPuzzle, rules *Rules, rand *rand.Rand, cfg *GenConfig) error {
+	var horRules, verRules int
+	*rules = nil
+	for attempt := 0; attempt < MAX_LAYOUT_ATTEMPTS; attempt++ {
+		_, err := GenPuzzleConfig(puzzle, rules, rand, cfg)
+		if err != nil {
+			return fmt.Errorf("generate puzzle: %w", err)
+		}
+		GetHintsQty(rules, &verRules, &horRules)
+		if horRules <= 24 && verRules <= 15 {
+			return nil
+		}
+		rules.Close()
+	}
+	return fmt.Errorf("no puzzle fits on the screen in %d attempts", MAX_LAYOUT_ATTEMPTS)
+}
+
+// GenLoggedPuzzle generates the puzzle of a move log again from the seed
+// and the difficulty of the game.
+func GenLoggedPuzzle(seed int64, difficulty string) (*SolvedPuzzle, Rules, error) {
+	if seed == 0 {
+		return nil, nil, errors.New("unknown seed")
+	}
+	var known bool
+	for _, d := range DIFFICULTIES {
+		known = known || d == difficulty
+	}
+	if !known {
+		return nil, nil, fmt.Errorf("puzzle of difficulty %q cannot be generated again", difficulty)
+	}
+
+	var puzzle SolvedPuzzle
+	var rules Rules
+	err := GenGamePuzzle(&puzzle, &rules, rand.New(rand.NewSource(seed)), NewGenConfigDifficulty(difficulty))
+	if err != nil {
+		return nil, nil, err
+	}
+	return &puzzle, rules, nil
+}
+
+// AddRedundantRules puts back up to qty rules from pool which are not in rules
+// yet, keeping their original order.
+func AddRedundantRules(rules *Rules, pool Rules, qty int, rand *rand.Rand) {
+	if qty <= 0 {
+		return
+	}
+
+	keep := make(map[Ruler]bool, len(*rules)+qty)
+	for _, r := range *rules {
+		keep[r] = true
+	}
+	var candidates []Ruler
+	for _, r := range pool {
+		if !keep[r] {
+			candidates = append(candidates, r)
 		}
-		Shuffle(&(*puzzle)[i], rand)
+	}
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	if qty < len(candidates) {
+		candidates = candidates[:qty]
+	}
+	for _, r := range candidates {
+		keep[r] = true
 	}
 
-	GenRules(puzzle, rules, rand)
-	RemoveRules(puzzle, rules)
+	var out Rules
+	for _, r := range pool {
+		if keep[r] {
+			out = append(out, r)
+		}
+	}
+	*rules = out
 }
 
 func OpenInitial(possib *Possibilities, rules *Rules, re RuleExcluder) {
@@ -298,7 +518,7 @@ func OpenInitial(possib *Possibilities, rules *Rules, re RuleExcluder) {
 			r.Apply(possib)
 		}
 	}
-	if options.AutoHints.value {
+	if options.AutoHints.Value() {
 		rules.ApplyHints(possib, re)
 	}
 }
@@ -335,12 +555,16 @@ func SavePuzzle(puzzle *SolvedPuzzle, stream io.Writer) {
 	}
 }
 
-func LoadPuzzle(puzzle *SolvedPuzzle, stream io.Reader) {
+func LoadPuzzle(puzzle *SolvedPuzzle, stream io.Reader) error {
 	for row := 0; row < PUZZLE_SIZE; row++ {
 		for col := 0; col < PUZZLE_SIZE; col++ {
-			puzzle[row][col].ReadFrom(stream)
+			err := puzzle[row][col].ReadFrom(stream)
+			if err != nil {
+				return err
+			}
 		}
 	}
+	return nil
 }
 
 func GetRule(rules *Rules, no int) Ruler {
@@ -366,6 +590,7 @@ const (
 type Ruler interface {
 	Close()
 	GetAsText() string
+	GetTag() string
 	Apply(*Possibilities) bool
 	ApplyOnStart() bool
 	GetShowOpts() ShowOptions
@@ -388,6 +613,14 @@ func (r Rule) GetShowOpts() ShowOptions {
 
 type Rules []Ruler
 
+// Close closes every rule and empties the list.
+func (rs *Rules) Close() {
+	for _, r := range *rs {
+		r.Close()
+	}
+	*rs = nil
+}
+
 func (rs *Rules) ApplyHints(pos *Possibilities, re RuleExcluder) {
 	updated := true
 	for updated {
@@ -1,6 +1,7 @@
 package goeinstein
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/veandco/go-sdl2/sdl"
@@ -19,7 +20,7 @@ const (
 type Selects [3]SelectedCard
 
 func (s Selects) Equal(row int, card Card) bool {
-	if !options.HighlightHints.value {
+	if !options.HighlightHints.Value() {
 		return false
 	}
 	for _, sel := range s {
@@ -50,8 +51,16 @@ func (s *SelectedCard) Equal(row int, card Card) bool {
 
 type Card uint8
 
-func (c *Card) ReadFrom(r io.Reader) {
-	*c = Card(ReadInt(r))
+func (c *Card) ReadFrom(r io.Reader) error {
+	v, err := ReadInt(r)
+	if err != nil {
+		return err
+	}
+	if v < 1 || v > PUZZLE_SIZE {
+		return fmt.Errorf("wrong card: %d", v)
+	}
+	*c = Card(v)
+	return nil
 }
 
 func (c *Card) WriteTo(w io.Writer) {
@@ -70,7 +79,8 @@ type Puzzle struct {
 	subHNo                  Card
 	winCommand, failCommand Command
 
-	hinter Hinter
+	hinter   Hinter
+	recorder MoveRecorder
 }
 
 type Hinter interface {
@@ -94,6 +104,15 @@ func NewPuzzle(is *IconSet, s *SolvedPuzzle, p *Possibilities, h Hinter) *Puzzle
 
 func (p *Puzzle) Close() {}
 
+// SetRecorder sets the recorder of the moves, it may be nil.
+func (p *Puzzle) SetRecorder(r MoveRecorder) { p.recorder = r }
+
+func (p *Puzzle) recordMove(kind, col, row int, element Card) {
+	if p.recorder != nil {
+		p.recorder.RecordMove(Move{Kind: kind, Col: col, Row: row, Card: element, AutoHint: options.AutoHints.Value()})
+	}
+}
+
 func (p *Puzzle) Reset() {
 	p.valid = true
 	p.win = false
@@ -179,22 +198,28 @@ func (p *Puzzle) OnMouseButtonDown(button uint8, x, y int32) bool {
 		if element == 0 {
 			return false
 		}
+		// auto hints are applied only after a recorded move, as on replay
+		moved := false
 		if button == 1 {
 			if p.possib.IsPossible(col, row, element) {
+				p.recordMove(MOVE_SET, col, row, element)
 				p.possib.Set(col, row, element)
 				sound.Play("laser.wav")
+				moved = true
 			}
 		} else if button == 3 {
 			if p.possib.IsPossible(col, row, element) {
+				p.recordMove(MOVE_EXCLUDE, col, row, element)
 				p.possib.Exclude(col, row, element)
 				sound.Play("whizz.wav")
+				moved = true
 			}
 			// else {
 			// 	p.possib.MakePossible(col, row, element)
 			// }
 		}
 
-		if options.AutoHints.value {
+		if moved && options.AutoHints.Value() {
 			p.hinter.AutoHint(p.possib)
 		}
 
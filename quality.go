@@ -0,0 +1,189 @@
+package goeinstein
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ItemReferrer is implemented by rules which can tell what items they mention.
+type ItemReferrer interface {
+	GetItems() []SelectedCard
+}
+
+// Canonicalizer is implemented by rules which have several equivalent forms,
+// e.g. "A is near to B" and "B is near to A".
+type Canonicalizer interface {
+	GetCanonical() string
+}
+
+// GetCanonical returns a string which is equal for semantically equal rules.
+func GetCanonical(r Ruler) string {
+	if c, ok := r.(Canonicalizer); ok {
+		return c.GetCanonical()
+	}
+	return r.GetTag() + ":" + r.GetAsText()
+}
+
+func GetItems(r Ruler) []SelectedCard {
+	if ir, ok := r.(ItemReferrer); ok {
+		return ir.GetItems()
+	}
+	return nil
+}
+
+type RejectReason string
+
+//nolint:golint,nosnakecase,stylecheck
+const (
+	REJECT_DUPLICATE   RejectReason = "duplicate"
+	REJECT_TYPE_CAP    RejectReason = "type cap"
+	REJECT_ITEM_CAP    RejectReason = "item cap"
+	REJECT_ROW_BALANCE RejectReason = "row balance"
+)
+
+type RejectEntry struct {
+	Reason RejectReason
+	Text   string
+}
+
+// QualityLog collects the reasons why rules and rule sets were rejected by
+// the generator. One log can be shared between many generated puzzles.
+type QualityLog struct {
+	entries []RejectEntry
+	counts  map[RejectReason]int
+}
+
+func NewQualityLog() *QualityLog {
+	return &QualityLog{
+		counts: make(map[RejectReason]int),
+	}
+}
+
+func (l *QualityLog) Add(reason RejectReason, text string) {
+	if l == nil {
+		return
+	}
+	l.entries = append(l.entries, RejectEntry{reason, text})
+	l.counts[reason]++
+}
+
+func (l *QualityLog) GetEntries() []RejectEntry       { return l.entries }
+func (l *QualityLog) GetCounts() map[RejectReason]int { return l.counts }
+
+func (l *QualityLog) String() string {
+	reasons := make([]string, 0, len(l.counts))
+	for r := range l.counts {
+		reasons = append(reasons, string(r))
+	}
+	sort.Strings(reasons)
+
+	var sb strings.Builder
+	for _, r := range reasons {
+		fmt.Fprintf(&sb, "%s: %d\n", r, l.counts[RejectReason(r)])
+	}
+	return sb.String()
+}
+
+// QualityConfig describes which rules and rule sets the generator accepts.
+type QualityConfig struct {
+	// MaxPerType caps the number of rules of a type, by rule tag.
+	// Types without an entry are not limited.
+	MaxPerType map[string]int
+	// MaxItemRefs caps how many rules may mention the same item.
+	// Zero means no limit.
+	MaxItemRefs int
+	// MaxRowShare caps the share of item mentions which refer to the same
+	// row in the final rule set. Zero means no limit.
+	MaxRowShare float64
+}
+
+func NewQualityConfig() *QualityConfig {
+	return &QualityConfig{
+		MaxPerType: map[string]int{
+			"open": 2,
+		},
+		MaxItemRefs: 5,
+		MaxRowShare: 0.3,
+	}
+}
+
+// QualityFilter checks rules against QualityConfig and logs every rejection.
+type QualityFilter struct {
+	config *QualityConfig
+	log    *QualityLog
+}
+
+func NewQualityFilter(cfg *QualityConfig, log *QualityLog) *QualityFilter {
+	return &QualityFilter{
+		config: cfg,
+		log:    log,
+	}
+}
+
+// Accept reports whether rule may be added to rules.
+func (f *QualityFilter) Accept(rules Rules, rule Ruler) bool {
+	canonical := GetCanonical(rule)
+	tag := rule.GetTag()
+	var typeCnt int
+	itemRefs := make(map[SelectedCard]int)
+	for _, r := range rules {
+		if GetCanonical(r) == canonical {
+			f.log.Add(REJECT_DUPLICATE, rule.GetAsText())
+			return false
+		}
+		if r.GetTag() == tag {
+			typeCnt++
+		}
+		for _, it := range GetItems(r) {
+			itemRefs[it]++
+		}
+	}
+
+	if f.config == nil {
+		return true
+	}
+
+	if max, ok := f.config.MaxPerType[tag]; ok && typeCnt >= max {
+		f.log.Add(REJECT_TYPE_CAP, rule.GetAsText())
+		return false
+	}
+
+	if f.config.MaxItemRefs > 0 {
+		for _, it := range GetItems(rule) {
+			if itemRefs[it] >= f.config.MaxItemRefs {
+				f.log.Add(REJECT_ITEM_CAP, rule.GetAsText())
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Check reports whether the final rule set is balanced.
+func (f *QualityFilter) Check(rules Rules) bool {
+	if f.config == nil || f.config.MaxRowShare <= 0 {
+		return true
+	}
+
+	var rowRefs [PUZZLE_SIZE]int
+	var total int
+	for _, r := range rules {
+		for _, it := range GetItems(r) {
+			rowRefs[it.row]++
+			total++
+		}
+	}
+	if total == 0 {
+		return true
+	}
+
+	for row, cnt := range rowRefs {
+		if float64(cnt)/float64(total) > f.config.MaxRowShare {
+			f.log.Add(REJECT_ROW_BALANCE, fmt.Sprintf("row %s is mentioned %d times of %d", string('A'+rune(row)), cnt, total))
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,373 @@
+package goeinstein
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// REPLAY_SPEEDS are the speeds of the replay viewer.
+//
+//nolint:golint,nosnakecase,stylecheck
+var REPLAY_SPEEDS = []int{1, 2, 4, 8, 16}
+
+type replayState struct {
+	possib      Possibilities
+	verExcluded []bool
+	horExcluded []bool
+}
+
+// MoveReplayer applies the moves of a log as the game did when they were
+// made, including the hints excluded by the rules, undo and redo.
+type MoveReplayer struct {
+	rules        Rules
+	possib       *Possibilities
+	verRules     []Ruler
+	horRules     []Ruler
+	verExcluded  []bool
+	horExcluded  []bool
+	showExcluded bool
+	undo         []replayState
+	redo         []replayState
+}
+
+var _ RuleExcluder = (*MoveReplayer)(nil)
+
+// NewMoveReplayer opens the puzzle of the rules as the game does.
+func NewMoveReplayer(rules Rules, initialHints bool) *MoveReplayer {
+	r := &MoveReplayer{
+		rules:  rules,
+		possib: NewPossibilities(),
+	}
+	for _, rule := range rules {
+		switch rule.GetShowOpts() {
+		case SHOW_VERT:
+			r.verRules = append(r.verRules, rule)
+		case SHOW_HORIZ:
+			r.horRules = append(r.horRules, rule)
+		case SHOW_NOTHING:
+		}
+	}
+	r.verExcluded = make([]bool, len(r.verRules))
+	r.horExcluded = make([]bool, len(r.horRules))
+
+	for _, rule := range rules {
+		if rule.ApplyOnStart() {
+			rule.Apply(r.possib)
+		}
+	}
+	if initialHints {
+		r.rules.ApplyHints(r.possib, r)
+	}
+	return r
+}
+
+func (r *MoveReplayer) GetPossibilities() *Possibilities { return r.possib }
+func (r *MoveReplayer) GetVerExcluded() []bool           { return r.verExcluded }
+func (r *MoveReplayer) GetHorExcluded() []bool           { return r.horExcluded }
+func (r *MoveReplayer) IsShowExcluded() bool             { return r.showExcluded }
+
+// ExcludeRule excludes the hints of the rule, as the hints lists do.
+func (r *MoveReplayer) ExcludeRule(rule Ruler) {
+	text := rule.GetAsText()
+	for i, vr := range r.verRules {
+		if vr.GetAsText() == text {
+			r.verExcluded[i] = true
+		}
+	}
+	for i, hr := range r.horRules {
+		if hr.GetAsText() == text {
+			r.horExcluded[i] = true
+		}
+	}
+}
+
+func (r *MoveReplayer) state() replayState {
+	return replayState{
+		possib:      *r.possib,
+		verExcluded: append([]bool(nil), r.verExcluded...),
+		horExcluded: append([]bool(nil), r.horExcluded...),
+	}
+}
+
+func (r *MoveReplayer) restore(s replayState) {
+	*r.possib = s.possib
+	r.verExcluded = s.verExcluded
+	r.horExcluded = s.horExcluded
+}
+
+func (r *MoveReplayer) getExcluded(row int) []bool {
+	switch ShowOptions(row) {
+	case SHOW_VERT:
+		return r.verExcluded
+	case SHOW_HORIZ:
+		return r.horExcluded
+	case SHOW_NOTHING:
+	}
+	return nil
+}
+
+// Apply makes the move. It fails if the game would not allow it.
+func (r *MoveReplayer) Apply(m Move) error {
+	switch m.Kind {
+	case MOVE_SET, MOVE_EXCLUDE:
+		if m.Col < 0 || m.Col >= PUZZLE_SIZE || m.Row < 0 || m.Row >= PUZZLE_SIZE || m.Card < 1 || m.Card > PUZZLE_SIZE {
+			return errors.New("move is out of the puzzle")
+		}
+		if r.possib.IsDefined(m.Col, m.Row) || !r.possib.IsPossible(m.Col, m.Row, m.Card) {
+			return errors.New("move is not possible")
+		}
+		r.undo = append(r.undo, r.state())
+		r.redo = nil
+		if m.Kind == MOVE_SET {
+			r.possib.Set(m.Col, m.Row, m.Card)
+		} else {
+			r.possib.Exclude(m.Col, m.Row, m.Card)
+		}
+		if m.AutoHint {
+			r.rules.ApplyHints(r.possib, r)
+		}
+	case MOVE_HINT_EXCLUDE, MOVE_HINT_RESTORE:
+		excluded := r.getExcluded(m.Row)
+		if m.Col < 0 || m.Col >= len(excluded) {
+			return errors.New("hint is out of the list")
+		}
+		exclude := m.Kind == MOVE_HINT_EXCLUDE
+		if excluded[m.Col] == exclude {
+			return errors.New("hint is not possible")
+		}
+		r.undo = append(r.undo, r.state())
+		r.redo = nil
+		r.getExcluded(m.Row)[m.Col] = exclude
+	case MOVE_SWITCH:
+		r.showExcluded = !r.showExcluded
+	case MOVE_PAUSE:
+	case MOVE_UNDO:
+		if len(r.undo) == 0 {
+			return errors.New("nothing to undo")
+		}
+		r.redo = append(r.redo, r.state())
+		r.restore(r.undo[len(r.undo)-1])
+		r.undo = r.undo[:len(r.undo)-1]
+	case MOVE_REDO:
+		if len(r.redo) == 0 {
+			return errors.New("nothing to redo")
+		}
+		r.undo = append(r.undo, r.state())
+		r.restore(r.redo[len(r.redo)-1])
+		r.redo = r.redo[:len(r.redo)-1]
+	default:
+		return fmt.Errorf("wrong move kind %d", m.Kind)
+	}
+	return nil
+}
+
+// ReplayPlayer shows the moves of a log on the widgets of the game.
+type ReplayPlayer struct {
+	Widget
+
+	solved   *SolvedPuzzle
+	rules    Rules
+	log      *MoveLog
+	replayer *MoveReplayer
+	pos      int
+	playing  bool
+	speed    int
+	clock    int // elapsed milliseconds of the game
+	lastTick uint64
+
+	iconSet  *IconSet
+	possib   *Possibilities
+	puzzle   *Puzzle
+	verHints *VertHints
+	horHints *HorHints
+	font     *Font
+}
+
+var _ TimerHandler = (*ReplayPlayer)(nil)
+
+func NewReplayPlayer(solved *SolvedPuzzle, rules Rules, l *MoveLog) *ReplayPlayer {
+	p := &ReplayPlayer{
+		solved: solved,
+		rules:  rules,
+		log:    l,
+	}
+	p.iconSet = NewIconSet()
+	p.possib = NewPossibilities()
+	p.puzzle = NewPuzzle(p.iconSet, solved, p.possib, nil)
+	p.verHints = NewVertHints(p.iconSet, &p.rules)
+	p.horHints = NewHorHints(p.iconSet, &p.rules)
+	p.font = NewFont("luximb.ttf", 16)
+	p.Seek(0)
+	return p
+}
+
+func (p *ReplayPlayer) Close() {
+	p.font.Close()
+	p.verHints.Close()
+	p.horHints.Close()
+	p.puzzle.Close()
+	p.iconSet.Close()
+}
+
+// Seek replays the log from the start up to the move pos.
+func (p *ReplayPlayer) Seek(pos int) {
+	if pos < 0 {
+		pos = 0
+	}
+	if pos > len(p.log.Moves) {
+		pos = len(p.log.Moves)
+	}
+	p.replayer = NewMoveReplayer(p.rules, p.log.InitialHints)
+	p.pos = 0
+	p.clock = 0
+	for p.pos < pos {
+		p.step()
+	}
+	p.update()
+}
+
+// Step makes the next move.
+func (p *ReplayPlayer) Step() {
+	if p.pos >= len(p.log.Moves) {
+		p.playing = false
+		return
+	}
+	p.step()
+	p.update()
+}
+
+func (p *ReplayPlayer) step() {
+	m := p.log.Moves[p.pos]
+	if err := p.replayer.Apply(m); err != nil {
+		log.Printf("Error on replay move %d: %v", p.pos, err)
+	}
+	p.pos++
+	p.clock = m.ElapsedMs
+}
+
+func (p *ReplayPlayer) update() {
+	*p.possib = *p.replayer.GetPossibilities()
+	p.verHints.SetExcluded(p.replayer.GetVerExcluded())
+	p.horHints.SetExcluded(p.replayer.GetHorExcluded())
+	if p.verHints.showExcluded != p.replayer.IsShowExcluded() {
+		p.verHints.ToggleExcluded()
+		p.horHints.ToggleExcluded()
+	}
+}
+
+func (p *ReplayPlayer) IsPlaying() bool { return p.playing }
+
+func (p *ReplayPlayer) TogglePlay() {
+	p.playing = !p.playing && p.pos < len(p.log.Moves)
+	p.lastTick = sdl.GetTicks64()
+}
+
+func (p *ReplayPlayer) GetSpeed() int { return REPLAY_SPEEDS[p.speed] }
+
+func (p *ReplayPlayer) NextSpeed() { p.speed = (p.speed + 1) % len(REPLAY_SPEEDS) }
+
+func (p *ReplayPlayer) OnTimer() {
+	if !p.playing {
+		return
+	}
+	now := sdl.GetTicks64()
+	p.clock += int(now-p.lastTick) * p.GetSpeed()
+	p.lastTick = now
+	clock := p.clock
+	var moved bool
+	for p.pos < len(p.log.Moves) && p.log.Moves[p.pos].ElapsedMs <= clock {
+		p.step()
+		moved = true
+	}
+	p.clock = clock
+	if moved {
+		p.update()
+	}
+	if p.pos >= len(p.log.Moves) {
+		p.playing = false
+	}
+}
+
+func (p *ReplayPlayer) Draw() {
+	p.puzzle.Draw()
+	p.verHints.Draw()
+	p.horHints.Draw()
+
+	s := fmt.Sprintf("%d / %d  %s", p.pos, len(p.log.Moves), SecToStr(uint64(p.clock/1000)))
+	var x int32 = 560
+	var y int32 = 24
+	w, h := p.font.GetSize(s)
+	rect := &sdl.Rect{x - 2, y - 2, 230, h + 4}
+	SDL_FillRect(screen.GetSurface(), rect, sdl.MapRGB(screen.GetSurface().Format, 0, 0, 255))
+	p.font.Draw(x+226-w, y, 255, 255, 255, true, s)
+	screen.AddRegionToUpdate(x-2, y-2, 230, h+4)
+}
+
+// ShowReplay shows the replay of the moves with play, pause, step and speed
+// controls.
+func ShowReplay(solved *SolvedPuzzle, rules Rules, l *MoveLog) {
+	player := NewReplayPlayer(solved, rules, l)
+	defer player.Close()
+	btnFont := NewFont("laudcn2.ttf", 14)
+	background := NewGameBackground()
+
+	for {
+		area := NewArea()
+		var closed bool
+		rebuild := func(fn func()) Command {
+			return FnCommand(func() {
+				fn()
+				area.FinishEventLoop()
+			})
+		}
+		area.SetTimer(50, player)
+		area.AddManaged(background, false)
+		area.AddManaged(player, false)
+
+		BUTTON := func(x, y int32, text string, cmd Command) {
+			area.Add(NewButtonTextBevel(x, y, 94, 30, btnFont, 255, 255, 0, "btn.bmp", text, false, cmd))
+		}
+
+		playCmd := FnCommand(player.TogglePlay)
+		BUTTON(12, 400, msg("replayPlay"), playCmd)
+		area.Add(NewKeyAccel(sdl.K_SPACE, playCmd))
+		backCmd := FnCommand(func() { player.Seek(player.pos - 1) })
+		BUTTON(119, 400, msg("replayBack"), backCmd)
+		area.Add(NewKeyAccel(sdl.K_LEFT, backCmd))
+		stepCmd := FnCommand(player.Step)
+		BUTTON(226, 400, msg("replayStep"), stepCmd)
+		area.Add(NewKeyAccel(sdl.K_RIGHT, stepCmd))
+		BUTTON(12, 440, msg("replayStart"), FnCommand(func() { player.Seek(0) }))
+		BUTTON(119, 440, fmt.Sprintf("%s %dx", msg("replaySpeed"), player.GetSpeed()), rebuild(player.NextSpeed))
+		exitCmd := rebuild(func() { closed = true })
+		BUTTON(226, 440, msg("exit"), exitCmd)
+		area.Add(NewKeyAccel(sdl.K_ESCAPE, exitCmd))
+
+		area.Run()
+
+		if closed || IsQuitRequested() {
+			return
+		}
+	}
+}
+
+// ShowEntryReplay generates the puzzle of the entry again and shows its
+// moves.
+func ShowEntryReplay(parentArea *Area, e *TopScoreEntry) {
+	l, err := DecodeMoveLog(e.Moves)
+	var solved *SolvedPuzzle
+	var rules Rules
+	if err == nil {
+		solved, rules, err = GenLoggedPuzzle(e.Seed, e.Difficulty)
+	}
+	if err != nil {
+		log.Printf("Error on open replay: %v", err)
+		font := NewFont("laudcn2.ttf", 16)
+		ShowMessageWindow(parentArea, "redpattern.bmp", 400, 80, font, 255, 255, 255, msg("replayError"))
+		return
+	}
+	ShowReplay(solved, rules, l)
+}
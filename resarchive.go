@@ -0,0 +1,153 @@
+package goeinstein
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// ResourceArchive is the einstein.res archive of the original game built by
+// mkres. Layout:
+//
+//	signature "CRF\0", major 2, minor 0, priority
+//	entries data
+//	directory: name, unpacked size, offset, packed size, level, group
+//	directory offset, entries count
+//
+// Strings are NUL-terminated, ints are 32-bit little-endian. Entries with
+// a non-zero level are zlib-compressed.
+type ResourceArchive struct {
+	fileName string
+	priority int
+	entries  map[string]resourceEntry
+}
+
+type resourceEntry struct {
+	unpackedSize int
+	offset       int
+	packedSize   int
+	level        int
+	group        string
+}
+
+// OpenResourceArchive reads the directory of the archive. The data is read
+// on Read.
+func OpenResourceArchive(fileName string) (*ResourceArchive, error) {
+	bs, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("read resource file (filename: %q): %w", fileName, err)
+	}
+	a, err := parseResourceArchive(bs)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", fileName, err)
+	}
+	a.fileName = fileName
+	return a, nil
+}
+
+func parseResourceArchive(bs []byte) (*ResourceArchive, error) {
+	if !bytes.HasPrefix(bs, []byte("CRF\x00")) || len(bs) < 24 {
+		return nil, errors.New("not a resource file")
+	}
+	header := bytes.NewReader(bs[4:16])
+	major, _ := ReadInt(header)
+	minor, _ := ReadInt(header)
+	priority, _ := ReadInt(header)
+	if major != 2 || minor != 0 {
+		return nil, fmt.Errorf("incompatible version of resource file: %d.%d", major, minor)
+	}
+
+	tail := bytes.NewReader(bs[len(bs)-8:])
+	start, _ := ReadInt(tail)
+	count, _ := ReadInt(tail)
+	if start < 16 || start > len(bs)-8 || count < 0 {
+		return nil, fmt.Errorf("wrong resource directory: offset %d, count %d", start, count)
+	}
+
+	a := &ResourceArchive{
+		priority: priority,
+		entries:  make(map[string]resourceEntry, count),
+	}
+	dir := bufio.NewReader(bytes.NewReader(bs[start : len(bs)-8]))
+	for i := 0; i < count; i++ {
+		name, err := ReadLegacyString(dir)
+		if err != nil {
+			return nil, fmt.Errorf("read resource name: %w", err)
+		}
+		var e resourceEntry
+		for _, v := range []*int{&e.unpackedSize, &e.offset, &e.packedSize, &e.level} {
+			*v, err = ReadInt(dir)
+			if err != nil {
+				return nil, fmt.Errorf("read resource %q: %w", name, err)
+			}
+		}
+		e.group, err = ReadLegacyString(dir)
+		if err != nil {
+			return nil, fmt.Errorf("read resource %q group: %w", name, err)
+		}
+		if e.offset < 16 || e.packedSize < 0 || e.offset+e.packedSize > start {
+			return nil, fmt.Errorf("resource %q is out of the file", name)
+		}
+		a.entries[name] = e
+	}
+	return a, nil
+}
+
+func (a *ResourceArchive) GetFileName() string { return a.fileName }
+func (a *ResourceArchive) GetPriority() int    { return a.priority }
+
+func (a *ResourceArchive) GetNames() []string {
+	names := make([]string, 0, len(a.entries))
+	for name := range a.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (a *ResourceArchive) Has(name string) bool {
+	_, ok := a.entries[name]
+	return ok
+}
+
+// Read returns the unpacked resource.
+func (a *ResourceArchive) Read(name string) ([]byte, error) {
+	e, ok := a.entries[name]
+	if !ok {
+		return nil, fmt.Errorf("resource %q not found in %q", name, a.fileName)
+	}
+
+	f, err := os.Open(a.fileName)
+	if err != nil {
+		return nil, fmt.Errorf("open resource file (filename: %q): %w", a.fileName, err)
+	}
+	defer f.Close()
+
+	packed := make([]byte, e.packedSize)
+	_, err = f.ReadAt(packed, int64(e.offset))
+	if err != nil {
+		return nil, fmt.Errorf("read resource %q: %w", name, err)
+	}
+	if e.level == 0 {
+		return packed, nil
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(packed))
+	if err != nil {
+		return nil, fmt.Errorf("unpack resource %q: %w", name, err)
+	}
+	defer zr.Close()
+	bs, err := io.ReadAll(io.LimitReader(zr, int64(e.unpackedSize)+1))
+	if err != nil {
+		return nil, fmt.Errorf("unpack resource %q: %w", name, err)
+	}
+	if len(bs) != e.unpackedSize {
+		return nil, fmt.Errorf("resource %q has size %d instead of %d", name, len(bs), e.unpackedSize)
+	}
+	return bs, nil
+}
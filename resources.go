@@ -2,8 +2,10 @@ package goeinstein
 
 import (
 	"embed"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"path/filepath"
 )
 
@@ -14,6 +16,8 @@ var resources = NewResources("res/")
 
 type Resources struct {
 	base string
+	// archives are used for files which are not embedded
+	archives []*ResourceArchive
 }
 
 func NewResources(basePath string) *Resources {
@@ -22,10 +26,26 @@ func NewResources(basePath string) *Resources {
 	return r
 }
 
-func (r Resources) GetRef(name string) []byte {
-	name = filepath.Join(r.base, name)
+func (r *Resources) AddArchive(a *ResourceArchive) {
+	r.archives = append(r.archives, a)
+}
 
-	file, err := resFS.Open(name)
+func (r *Resources) GetRef(name string) []byte {
+	fullName := filepath.Join(r.base, name)
+
+	file, err := resFS.Open(fullName)
+	if errors.Is(err, fs.ErrNotExist) {
+		for _, a := range r.archives {
+			if a.Has(name) {
+				bs, err := a.Read(name)
+				if err != nil {
+					panic(fmt.Errorf("read %q file: %w", name, err))
+				}
+				return bs
+			}
+		}
+	}
+	name = fullName
 	if err != nil {
 		panic(fmt.Errorf("open %q file: %w", name, err))
 	}
@@ -40,4 +60,4 @@ func (r Resources) GetRef(name string) []byte {
 	return bs
 }
 
-func (r Resources) DelRef(_ []byte) {}
+func (r *Resources) DelRef(_ []byte) {}
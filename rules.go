@@ -1,6 +1,7 @@
 package goeinstein
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"math/rand"
@@ -27,6 +28,82 @@ func GetThingName(row int, thing Card) string {
 	return s
 }
 
+// readRuleThing reads a row and a thing mentioned by a rule.
+func readRuleThing(stream io.Reader, row *int, thing *Card) error {
+	var err error
+	*row, err = ReadInt(stream)
+	if err != nil {
+		return err
+	}
+	if *row < 0 || *row >= PUZZLE_SIZE {
+		return fmt.Errorf("wrong row: %d", *row)
+	}
+	return thing.ReadFrom(stream)
+}
+
+// ruleThing is a row and a thing of a rule in JSON, stored under rowKey and
+// thingKey.
+type ruleThing struct {
+	rowKey, thingKey string
+	row              *int
+	thing            *Card
+}
+
+// numberedThing stores the row and the thing under row<n> and thing<n>.
+func numberedThing(n string, row *int, thing *Card) ruleThing {
+	return ruleThing{"row" + n, "thing" + n, row, thing}
+}
+
+// marshalRuleThings encodes the column, if it is not nil, and the things of a
+// rule as a JSON object.
+func marshalRuleThings(col *int, things ...ruleThing) ([]byte, error) {
+	m := make(map[string]int, len(things)*2+1)
+	if col != nil {
+		m["col"] = *col
+	}
+	for _, t := range things {
+		m[t.rowKey] = *t.row
+		m[t.thingKey] = int(*t.thing)
+	}
+	return json.Marshal(m)
+}
+
+// unmarshalRuleThings decodes an object written by marshalRuleThings. As
+// readRuleThing, it fails if a column, a row or a thing is out of the puzzle.
+func unmarshalRuleThings(bs []byte, col *int, things ...ruleThing) error {
+	var m map[string]int
+	err := json.Unmarshal(bs, &m)
+	if err != nil {
+		return err
+	}
+	get := func(key string, min, max int) (int, error) {
+		v, ok := m[key]
+		if !ok {
+			return 0, fmt.Errorf("missing %q", key)
+		}
+		if v < min || v > max {
+			return 0, fmt.Errorf("wrong %s: %d", key, v)
+		}
+		return v, nil
+	}
+	if col != nil {
+		if *col, err = get("col", 0, PUZZLE_SIZE-1); err != nil {
+			return err
+		}
+	}
+	for _, t := range things {
+		if *t.row, err = get(t.rowKey, 0, PUZZLE_SIZE-1); err != nil {
+			return err
+		}
+		thing, err := get(t.thingKey, 1, PUZZLE_SIZE)
+		if err != nil {
+			return err
+		}
+		*t.thing = Card(thing)
+	}
+	return nil
+}
+
 // NearRule
 //
 // A <> 5
@@ -41,8 +118,31 @@ type NearRule struct {
 
 var _ Ruler = (*NearRule)(nil)
 var _ HintApplier = (*NearRule)(nil)
+var _ ItemReferrer = (*NearRule)(nil)
+var _ CNFEncoder = (*NearRule)(nil)
+var _ MiniZincEncoder = (*NearRule)(nil)
+var _ Canonicalizer = (*NearRule)(nil)
+
+var nearRuleType = &RuleType{
+	Tag:    "near",
+	Title:  "ruleNear",
+	Weight: 4,
+	Load: func(stream io.Reader) (Ruler, error) {
+		r, err := NewNearRuleStream(stream)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	},
+	Generate: func(puzzle *SolvedPuzzle, rand *rand.Rand) Ruler {
+		return NewNearRule(*puzzle, rand)
+	},
+	MarshalJSON:   MarshalRuleJSON,
+	UnmarshalJSON: UnmarshalRuleJSON(func() Ruler { return &NearRule{} }),
+}
 
 func (r *NearRule) GetShowOpts() ShowOptions { return SHOW_HORIZ }
+func (r *NearRule) GetTag() string           { return "near" }
 
 func NewNearRule(puzzle SolvedPuzzle, rand *rand.Rand) *NearRule {
 	r := &NearRule{}
@@ -70,13 +170,17 @@ func NewNearRule(puzzle SolvedPuzzle, rand *rand.Rand) *NearRule {
 	return r
 }
 
-func NewNearRuleStream(stream io.Reader) *NearRule {
+func NewNearRuleStream(stream io.Reader) (*NearRule, error) {
 	r := &NearRule{}
-	r.row1 = ReadInt(stream)
-	r.thing1.ReadFrom(stream)
-	r.row2 = ReadInt(stream)
-	r.thing2.ReadFrom(stream)
-	return r
+	err := readRuleThing(stream, &r.row1, &r.thing1)
+	if err != nil {
+		return nil, err
+	}
+	err = readRuleThing(stream, &r.row2, &r.thing2)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
 }
 
 func (r *NearRule) ApplyToCol(pos *Possibilities, col int, nearRow int, nearNum Card, thisRow int, thisNum Card) bool {
@@ -152,6 +256,18 @@ func (r *NearRule) GetAsText() string {
 	return GetThingName(r.row1, r.thing1) + " is near to " + GetThingName(r.row2, r.thing2)
 }
 
+func (r *NearRule) GetItems() []SelectedCard {
+	return []SelectedCard{{r.row1, r.thing1}, {r.row2, r.thing2}}
+}
+
+func (r *NearRule) GetCanonical() string {
+	a, b := GetThingName(r.row1, r.thing1), GetThingName(r.row2, r.thing2)
+	if a > b {
+		a, b = b, a
+	}
+	return "near:" + a + ":" + b
+}
+
 func (r *NearRule) Draw(x, y int32, iconSet *IconSet, h bool) {
 	icon := iconSet.GetLargeIcon(r.row1, r.thing1, h)
 	screen.Draw(x, y, icon)
@@ -173,6 +289,41 @@ func (r *NearRule) Save(stream io.Writer) {
 	r.thing2.WriteTo(stream)
 }
 
+func (r *NearRule) EncodeCNF(size int) [][]int {
+	var cls [][]int
+	near := func(row1 int, thing1 Card, row2 int, thing2 Card) {
+		for col := 0; col < size; col++ {
+			cl := []int{-CNFVar(size, row1, col, thing1)}
+			if col > 0 {
+				cl = append(cl, CNFVar(size, row2, col-1, thing2))
+			}
+			if col < size-1 {
+				cl = append(cl, CNFVar(size, row2, col+1, thing2))
+			}
+			cls = append(cls, cl)
+		}
+	}
+	near(r.row1, r.thing1, r.row2, r.thing2)
+	near(r.row2, r.thing2, r.row1, r.thing1)
+	return cls
+}
+
+func (r *NearRule) EncodeMiniZinc() string {
+	return "abs(" + mznPos(r.row1, r.thing1) + " - " + mznPos(r.row2, r.thing2) + ") = 1"
+}
+
+func (r *NearRule) jsonThings() []ruleThing {
+	return []ruleThing{numberedThing("1", &r.row1, &r.thing1), numberedThing("2", &r.row2, &r.thing2)}
+}
+
+func (r *NearRule) MarshalJSON() ([]byte, error) {
+	return marshalRuleThings(nil, r.jsonThings()...)
+}
+
+func (r *NearRule) UnmarshalJSON(bs []byte) error {
+	return unmarshalRuleThings(bs, nil, r.jsonThings()...)
+}
+
 func (r *NearRule) OnMouseMove() {
 	Selected.Clear()
 	Selected[0].row = r.row1
@@ -195,8 +346,30 @@ type DirectionRule struct {
 
 var _ Ruler = (*DirectionRule)(nil)
 var _ HintApplier = (*DirectionRule)(nil)
+var _ ItemReferrer = (*DirectionRule)(nil)
+var _ CNFEncoder = (*DirectionRule)(nil)
+var _ MiniZincEncoder = (*DirectionRule)(nil)
+
+var directionRuleType = &RuleType{
+	Tag:    "direction",
+	Title:  "ruleDirection",
+	Weight: 4,
+	Load: func(stream io.Reader) (Ruler, error) {
+		r, err := NewDirectionRuleStream(stream)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	},
+	Generate: func(puzzle *SolvedPuzzle, rand *rand.Rand) Ruler {
+		return NewDirectionRule(*puzzle, rand)
+	},
+	MarshalJSON:   MarshalRuleJSON,
+	UnmarshalJSON: UnmarshalRuleJSON(func() Ruler { return &DirectionRule{} }),
+}
 
 func (r *DirectionRule) GetShowOpts() ShowOptions { return SHOW_HORIZ }
+func (r *DirectionRule) GetTag() string           { return "direction" }
 
 func NewDirectionRule(puzzle SolvedPuzzle, rand *rand.Rand) *DirectionRule {
 	r := &DirectionRule{}
@@ -209,13 +382,17 @@ func NewDirectionRule(puzzle SolvedPuzzle, rand *rand.Rand) *DirectionRule {
 	return r
 }
 
-func NewDirectionRuleStream(stream io.Reader) *DirectionRule {
+func NewDirectionRuleStream(stream io.Reader) (*DirectionRule, error) {
 	r := &DirectionRule{}
-	r.row1 = ReadInt(stream)
-	r.thing1.ReadFrom(stream)
-	r.row2 = ReadInt(stream)
-	r.thing2.ReadFrom(stream)
-	return r
+	err := readRuleThing(stream, &r.row1, &r.thing1)
+	if err != nil {
+		return nil, err
+	}
+	err = readRuleThing(stream, &r.row2, &r.thing2)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
 }
 
 func (r *DirectionRule) Apply(pos *Possibilities) bool {
@@ -248,6 +425,10 @@ func (r *DirectionRule) GetAsText() string {
 	return GetThingName(r.row1, r.thing1) + " is from the left of " + GetThingName(r.row2, r.thing2)
 }
 
+func (r *DirectionRule) GetItems() []SelectedCard {
+	return []SelectedCard{{r.row1, r.thing1}, {r.row2, r.thing2}}
+}
+
 func (r *DirectionRule) Draw(x, y int32, iconSet *IconSet, h bool) {
 	icon := iconSet.GetLargeIcon(r.row1, r.thing1, h)
 	screen.Draw(x, y, icon)
@@ -280,6 +461,32 @@ func (r *DirectionRule) ApplyHint(pos *Possibilities, re RuleExcluder) bool {
 	return out
 }
 
+func (r *DirectionRule) EncodeCNF(size int) [][]int {
+	var cls [][]int
+	for col1 := 0; col1 < size; col1++ {
+		for col2 := 0; col2 <= col1; col2++ {
+			cls = append(cls, []int{-CNFVar(size, r.row1, col1, r.thing1), -CNFVar(size, r.row2, col2, r.thing2)})
+		}
+	}
+	return cls
+}
+
+func (r *DirectionRule) EncodeMiniZinc() string {
+	return mznPos(r.row1, r.thing1) + " < " + mznPos(r.row2, r.thing2)
+}
+
+func (r *DirectionRule) jsonThings() []ruleThing {
+	return []ruleThing{numberedThing("1", &r.row1, &r.thing1), numberedThing("2", &r.row2, &r.thing2)}
+}
+
+func (r *DirectionRule) MarshalJSON() ([]byte, error) {
+	return marshalRuleThings(nil, r.jsonThings()...)
+}
+
+func (r *DirectionRule) UnmarshalJSON(bs []byte) error {
+	return unmarshalRuleThings(bs, nil, r.jsonThings()...)
+}
+
 func (r *DirectionRule) OnMouseMove() {
 	Selected.Clear()
 	Selected[0].row = r.row1
@@ -296,10 +503,32 @@ type OpenRule struct {
 }
 
 var _ Ruler = (*OpenRule)(nil)
+var _ ItemReferrer = (*OpenRule)(nil)
+var _ CNFEncoder = (*OpenRule)(nil)
+var _ MiniZincEncoder = (*OpenRule)(nil)
+
+var openRuleType = &RuleType{
+	Tag:    "open",
+	Title:  "ruleOpen",
+	Weight: 1,
+	Load: func(stream io.Reader) (Ruler, error) {
+		r, err := NewOpenRuleStream(stream)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	},
+	Generate: func(puzzle *SolvedPuzzle, rand *rand.Rand) Ruler {
+		return NewOpenRule(*puzzle, rand)
+	},
+	MarshalJSON:   MarshalRuleJSON,
+	UnmarshalJSON: UnmarshalRuleJSON(func() Ruler { return &OpenRule{} }),
+}
 
 func (r *OpenRule) ApplyOnStart() bool                                  { return true }
 func (r *OpenRule) Draw(x, y int32, iconSet *IconSet, highlighted bool) {}
 func (r *OpenRule) GetShowOpts() ShowOptions                            { return SHOW_NOTHING }
+func (r *OpenRule) GetTag() string                                      { return "open" }
 
 func NewOpenRule(puzzle SolvedPuzzle, rand *rand.Rand) *OpenRule {
 	r := &OpenRule{}
@@ -309,12 +538,21 @@ func NewOpenRule(puzzle SolvedPuzzle, rand *rand.Rand) *OpenRule {
 	return r
 }
 
-func NewOpenRuleStream(stream io.Reader) *OpenRule {
+func NewOpenRuleStream(stream io.Reader) (*OpenRule, error) {
 	r := &OpenRule{}
-	r.col = ReadInt(stream)
-	r.row = ReadInt(stream)
-	r.thing.ReadFrom(stream)
-	return r
+	var err error
+	r.col, err = ReadInt(stream)
+	if err != nil {
+		return nil, err
+	}
+	if r.col < 0 || r.col >= PUZZLE_SIZE {
+		return nil, fmt.Errorf("wrong column: %d", r.col)
+	}
+	err = readRuleThing(stream, &r.row, &r.thing)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
 }
 
 func (r *OpenRule) Apply(pos *Possibilities) bool {
@@ -329,6 +567,10 @@ func (r *OpenRule) GetAsText() string {
 	return GetThingName(r.row, r.thing) + " is at column " + ToString(r.col+1)
 }
 
+func (r *OpenRule) GetItems() []SelectedCard {
+	return []SelectedCard{{r.row, r.thing}}
+}
+
 func (r *OpenRule) Save(stream io.Writer) {
 	WriteString(stream, "open")
 	WriteInt(stream, r.col)
@@ -336,6 +578,22 @@ func (r *OpenRule) Save(stream io.Writer) {
 	r.thing.WriteTo(stream)
 }
 
+func (r *OpenRule) EncodeCNF(size int) [][]int {
+	return [][]int{{CNFVar(size, r.row, r.col, r.thing)}}
+}
+
+func (r *OpenRule) EncodeMiniZinc() string {
+	return mznPos(r.row, r.thing) + " = " + ToString(r.col+1)
+}
+
+func (r *OpenRule) MarshalJSON() ([]byte, error) {
+	return marshalRuleThings(&r.col, numberedThing("", &r.row, &r.thing))
+}
+
+func (r *OpenRule) UnmarshalJSON(bs []byte) error {
+	return unmarshalRuleThings(bs, &r.col, numberedThing("", &r.row, &r.thing))
+}
+
 func (r *OpenRule) OnMouseMove() {}
 
 // UnderRule
@@ -351,8 +609,31 @@ type UnderRule struct {
 
 var _ Ruler = (*UnderRule)(nil)
 var _ HintApplier = (*UnderRule)(nil)
+var _ ItemReferrer = (*UnderRule)(nil)
+var _ CNFEncoder = (*UnderRule)(nil)
+var _ MiniZincEncoder = (*UnderRule)(nil)
+var _ Canonicalizer = (*UnderRule)(nil)
+
+var underRuleType = &RuleType{
+	Tag:    "under",
+	Title:  "ruleUnder",
+	Weight: 2,
+	Load: func(stream io.Reader) (Ruler, error) {
+		r, err := NewUnderRuleStream(stream)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	},
+	Generate: func(puzzle *SolvedPuzzle, rand *rand.Rand) Ruler {
+		return NewUnderRule(*puzzle, rand)
+	},
+	MarshalJSON:   MarshalRuleJSON,
+	UnmarshalJSON: UnmarshalRuleJSON(func() Ruler { return &UnderRule{} }),
+}
 
 func (*UnderRule) GetShowOpts() ShowOptions { return SHOW_VERT }
+func (*UnderRule) GetTag() string           { return "under" }
 
 func NewUnderRule(puzzle SolvedPuzzle, rand *rand.Rand) *UnderRule {
 	r := &UnderRule{}
@@ -369,13 +650,17 @@ func NewUnderRule(puzzle SolvedPuzzle, rand *rand.Rand) *UnderRule {
 	return r
 }
 
-func NewUnderRuleStream(stream io.Reader) *UnderRule {
+func NewUnderRuleStream(stream io.Reader) (*UnderRule, error) {
 	r := &UnderRule{}
-	r.row1 = ReadInt(stream)
-	r.thing1.ReadFrom(stream)
-	r.row2 = ReadInt(stream)
-	r.thing2.ReadFrom(stream)
-	return r
+	err := readRuleThing(stream, &r.row1, &r.thing1)
+	if err != nil {
+		return nil, err
+	}
+	err = readRuleThing(stream, &r.row2, &r.thing2)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
 }
 
 func (r *UnderRule) Apply(pos *Possibilities) bool {
@@ -410,6 +695,18 @@ func (r *UnderRule) GetAsText() string {
 	return GetThingName(r.row1, r.thing1) + " is the same column as " + GetThingName(r.row2, r.thing2)
 }
 
+func (r *UnderRule) GetItems() []SelectedCard {
+	return []SelectedCard{{r.row1, r.thing1}, {r.row2, r.thing2}}
+}
+
+func (r *UnderRule) GetCanonical() string {
+	a, b := GetThingName(r.row1, r.thing1), GetThingName(r.row2, r.thing2)
+	if a > b {
+		a, b = b, a
+	}
+	return "under:" + a + ":" + b
+}
+
 func (r *UnderRule) Draw(x, y int32, iconSet *IconSet, h bool) {
 	icon := iconSet.GetLargeIcon(r.row1, r.thing1, h)
 	screen.Draw(x, y, icon)
@@ -430,6 +727,32 @@ func (r *UnderRule) Save(stream io.Writer) {
 	r.thing2.WriteTo(stream)
 }
 
+func (r *UnderRule) EncodeCNF(size int) [][]int {
+	var cls [][]int
+	for col := 0; col < size; col++ {
+		v1 := CNFVar(size, r.row1, col, r.thing1)
+		v2 := CNFVar(size, r.row2, col, r.thing2)
+		cls = append(cls, []int{-v1, v2}, []int{v1, -v2})
+	}
+	return cls
+}
+
+func (r *UnderRule) EncodeMiniZinc() string {
+	return mznPos(r.row1, r.thing1) + " = " + mznPos(r.row2, r.thing2)
+}
+
+func (r *UnderRule) jsonThings() []ruleThing {
+	return []ruleThing{numberedThing("1", &r.row1, &r.thing1), numberedThing("2", &r.row2, &r.thing2)}
+}
+
+func (r *UnderRule) MarshalJSON() ([]byte, error) {
+	return marshalRuleThings(nil, r.jsonThings()...)
+}
+
+func (r *UnderRule) UnmarshalJSON(bs []byte) error {
+	return unmarshalRuleThings(bs, nil, r.jsonThings()...)
+}
+
 func (r *UnderRule) OnMouseMove() {
 	Selected.Clear()
 	Selected[0].row = r.row1
@@ -451,8 +774,31 @@ type BetweenRule struct {
 
 var _ Ruler = (*BetweenRule)(nil)
 var _ HintApplier = (*BetweenRule)(nil)
+var _ ItemReferrer = (*BetweenRule)(nil)
+var _ CNFEncoder = (*BetweenRule)(nil)
+var _ MiniZincEncoder = (*BetweenRule)(nil)
+var _ Canonicalizer = (*BetweenRule)(nil)
+
+var betweenRuleType = &RuleType{
+	Tag:    "between",
+	Title:  "ruleBetween",
+	Weight: 3,
+	Load: func(stream io.Reader) (Ruler, error) {
+		r, err := NewBetweenRuleStream(stream)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	},
+	Generate: func(puzzle *SolvedPuzzle, rand *rand.Rand) Ruler {
+		return NewBetweenRule(*puzzle, rand)
+	},
+	MarshalJSON:   MarshalRuleJSON,
+	UnmarshalJSON: UnmarshalRuleJSON(func() Ruler { return &BetweenRule{} }),
+}
 
 func (r *BetweenRule) GetShowOpts() ShowOptions { return SHOW_HORIZ }
+func (r *BetweenRule) GetTag() string           { return "between" }
 
 func NewBetweenRule(puzzle SolvedPuzzle, rand *rand.Rand) *BetweenRule {
 	r := &BetweenRule{}
@@ -472,15 +818,21 @@ func NewBetweenRule(puzzle SolvedPuzzle, rand *rand.Rand) *BetweenRule {
 	return r
 }
 
-func NewBetweenRuleStream(stream io.Reader) *BetweenRule {
+func NewBetweenRuleStream(stream io.Reader) (*BetweenRule, error) {
 	r := &BetweenRule{}
-	r.row1 = ReadInt(stream)
-	r.thing1.ReadFrom(stream)
-	r.row2 = ReadInt(stream)
-	r.thing2.ReadFrom(stream)
-	r.centerRow = ReadInt(stream)
-	r.centerThing.ReadFrom(stream)
-	return r
+	err := readRuleThing(stream, &r.row1, &r.thing1)
+	if err != nil {
+		return nil, err
+	}
+	err = readRuleThing(stream, &r.row2, &r.thing2)
+	if err != nil {
+		return nil, err
+	}
+	err = readRuleThing(stream, &r.centerRow, &r.centerThing)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
 }
 
 func (r *BetweenRule) Apply(pos *Possibilities) bool {
@@ -562,6 +914,18 @@ func (r *BetweenRule) GetAsText() string {
 	return GetThingName(r.centerRow, r.centerThing) + " is between " + GetThingName(r.row1, r.thing1) + " and " + GetThingName(r.row2, r.thing2)
 }
 
+func (r *BetweenRule) GetItems() []SelectedCard {
+	return []SelectedCard{{r.row1, r.thing1}, {r.centerRow, r.centerThing}, {r.row2, r.thing2}}
+}
+
+func (r *BetweenRule) GetCanonical() string {
+	a, b := GetThingName(r.row1, r.thing1), GetThingName(r.row2, r.thing2)
+	if a > b {
+		a, b = b, a
+	}
+	return "between:" + GetThingName(r.centerRow, r.centerThing) + ":" + a + ":" + b
+}
+
 func (r *BetweenRule) Draw(x, y int32, iconSet *IconSet, h bool) {
 	icon := iconSet.GetLargeIcon(r.row1, r.thing1, h)
 	screen.Draw(x, y, icon)
@@ -645,6 +1009,50 @@ func (r *BetweenRule) ApplyHint(pos *Possibilities, re RuleExcluder) bool {
 	return out
 }
 
+func (r *BetweenRule) EncodeCNF(size int) [][]int {
+	var cls [][]int
+	for col := 0; col < size; col++ {
+		center := -CNFVar(size, r.centerRow, col, r.centerThing)
+		if col == 0 || col == size-1 {
+			cls = append(cls, []int{center})
+			continue
+		}
+		// center -> (1 left and 2 right) or (1 right and 2 left)
+		left := []int{CNFVar(size, r.row1, col-1, r.thing1), CNFVar(size, r.row2, col+1, r.thing2)}
+		right := []int{CNFVar(size, r.row1, col+1, r.thing1), CNFVar(size, r.row2, col-1, r.thing2)}
+		for _, a := range left {
+			for _, b := range right {
+				cls = append(cls, []int{center, a, b})
+			}
+		}
+	}
+	return cls
+}
+
+func (r *BetweenRule) EncodeMiniZinc() string {
+	c := mznPos(r.centerRow, r.centerThing)
+	p1 := mznPos(r.row1, r.thing1)
+	p2 := mznPos(r.row2, r.thing2)
+	return "(" + p1 + " = " + c + " - 1 /\\ " + p2 + " = " + c + " + 1) \\/ (" +
+		p1 + " = " + c + " + 1 /\\ " + p2 + " = " + c + " - 1)"
+}
+
+func (r *BetweenRule) jsonThings() []ruleThing {
+	return []ruleThing{
+		numberedThing("1", &r.row1, &r.thing1),
+		numberedThing("2", &r.row2, &r.thing2),
+		{"centerRow", "centerThing", &r.centerRow, &r.centerThing},
+	}
+}
+
+func (r *BetweenRule) MarshalJSON() ([]byte, error) {
+	return marshalRuleThings(nil, r.jsonThings()...)
+}
+
+func (r *BetweenRule) UnmarshalJSON(bs []byte) error {
+	return unmarshalRuleThings(bs, nil, r.jsonThings()...)
+}
+
 func (r *BetweenRule) OnMouseMove() {
 	Selected.Clear()
 	Selected[0].row = r.row1
@@ -655,22 +1063,55 @@ func (r *BetweenRule) OnMouseMove() {
 	Selected[2].card = r.centerThing
 }
 
+// RuleWeights maps rule tags to relative probabilities of generating rules
+// of that type. Types with zero weight are never generated.
+type RuleWeights map[string]int
+
+// NewRuleWeights returns default weights of the registered rule types.
+func NewRuleWeights() RuleWeights {
+	w := make(RuleWeights)
+	for _, t := range GetRuleTypes() {
+		w[t.Tag] = t.Weight
+	}
+	return w
+}
+
+func (w RuleWeights) Total() int {
+	var total int
+	for _, t := range GetRuleTypes() {
+		total += w[t.Tag]
+	}
+	return total
+}
+
+func (w RuleWeights) Clone() RuleWeights {
+	out := make(RuleWeights, len(w))
+	for k, v := range w {
+		out[k] = v
+	}
+	return out
+}
+
 func GenRule(puzzle *SolvedPuzzle, rand *rand.Rand) Ruler {
-	a := rand.Intn(14)
-	switch a {
-	case 0, 1, 2, 3:
-		return NewNearRule(*puzzle, rand)
-	case 4:
-		return NewOpenRule(*puzzle, rand)
-	case 5, 6:
-		return NewUnderRule(*puzzle, rand)
-	case 7, 8, 9, 10:
-		return NewDirectionRule(*puzzle, rand)
-	case 11, 12, 13:
-		return NewBetweenRule(*puzzle, rand)
-	default:
-		return GenRule(puzzle, rand)
+	return GenRuleWeights(puzzle, rand, NewRuleWeights())
+}
+
+// GenRuleWeights picks a rule type by weights, in the order of registration,
+// and generates a rule of it.
+func GenRuleWeights(puzzle *SolvedPuzzle, rand *rand.Rand, weights RuleWeights) Ruler {
+	total := weights.Total()
+	if total <= 0 {
+		return nil
+	}
+
+	a := rand.Intn(total)
+	for _, t := range GetRuleTypes() {
+		if a < weights[t.Tag] {
+			return t.Generate(puzzle, rand)
+		}
+		a -= weights[t.Tag]
 	}
+	return nil
 }
 
 func SaveRules(rules *Rules, stream io.Writer) {
@@ -680,25 +1121,26 @@ func SaveRules(rules *Rules, stream io.Writer) {
 	}
 }
 
-func LoadRules(rules *Rules, stream io.Reader) {
-	no := ReadInt(stream)
+func LoadRules(rules *Rules, stream io.Reader) error {
+	no, err := ReadInt(stream)
+	if err != nil {
+		return fmt.Errorf("read rules count: %w", err)
+	}
 
 	for i := 0; i < no; i++ {
-		ruleType := ReadString(stream)
-		var r Ruler
-		if ruleType == "near" { //nolint:gocritic
-			r = NewNearRuleStream(stream)
-		} else if ruleType == "open" {
-			r = NewOpenRuleStream(stream)
-		} else if ruleType == "under" {
-			r = NewUnderRuleStream(stream)
-		} else if ruleType == "direction" {
-			r = NewDirectionRuleStream(stream)
-		} else if ruleType == "between" {
-			r = NewBetweenRuleStream(stream)
-		} else {
-			panic(fmt.Errorf("invalid rule type: %q", ruleType))
+		ruleType, err := ReadString(stream)
+		if err != nil {
+			return fmt.Errorf("read rule type: %w", err)
+		}
+		t, ok := GetRuleType(ruleType)
+		if !ok {
+			return fmt.Errorf("invalid rule type: %q", ruleType)
+		}
+		r, err := t.Load(stream)
+		if err != nil {
+			return fmt.Errorf("load %q rule: %w", ruleType, err)
 		}
 		*rules = append(*rules, r)
 	}
+	return nil
 }
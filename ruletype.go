@@ -0,0 +1,145 @@
+package goeinstein
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+)
+
+// RuleType describes a kind of rule: how it is stored, generated and shown.
+// Rule types from other packages are added with RegisterRuleType.
+type RuleType struct {
+	// Tag identifies the rule type in save files and generator weights.
+	// Rules of the type must return it from Ruler.GetTag.
+	Tag string
+	// Title is the message key of the rule type name.
+	Title string
+	// Weight is the default generator weight.
+	Weight int
+	// Load reads a rule written by Ruler.Save after the tag.
+	Load func(io.Reader) (Ruler, error)
+	// Generate creates a random rule which is true for the puzzle.
+	Generate func(*SolvedPuzzle, *rand.Rand) Ruler
+	// MarshalJSON and UnmarshalJSON encode a rule of the type as JSON.
+	MarshalJSON   func(Ruler) ([]byte, error)
+	UnmarshalJSON func([]byte) (Ruler, error)
+	// Draw renders a rule in the hints panels. If it is nil, Ruler.Draw
+	// is used.
+	Draw func(r Ruler, x, y int32, iconSet *IconSet, highlighted bool)
+}
+
+type RuleTypes struct {
+	types []*RuleType
+	byTag map[string]*RuleType
+}
+
+func NewRuleTypes(ts ...*RuleType) *RuleTypes {
+	r := &RuleTypes{
+		byTag: make(map[string]*RuleType),
+	}
+	for _, t := range ts {
+		r.Register(t)
+	}
+	return r
+}
+
+// Register adds the rule type. It panics if the tag is already registered.
+func (r *RuleTypes) Register(t *RuleType) {
+	if _, ok := r.byTag[t.Tag]; ok {
+		panic(fmt.Errorf("rule type %q is already registered", t.Tag))
+	}
+	r.types = append(r.types, t)
+	r.byTag[t.Tag] = t
+}
+
+func (r *RuleTypes) Get(tag string) (*RuleType, bool) {
+	t, ok := r.byTag[tag]
+	return t, ok
+}
+
+// GetAll returns rule types in the order of registration.
+func (r *RuleTypes) GetAll() []*RuleType { return r.types }
+
+var ruleTypes = NewRuleTypes(
+	nearRuleType,
+	openRuleType,
+	underRuleType,
+	directionRuleType,
+	betweenRuleType,
+)
+
+func RegisterRuleType(t *RuleType)             { ruleTypes.Register(t) }
+func GetRuleType(tag string) (*RuleType, bool) { return ruleTypes.Get(tag) }
+func GetRuleTypes() []*RuleType                { return ruleTypes.GetAll() }
+
+// DrawRule renders the rule with the renderer of its type.
+func DrawRule(r Ruler, x, y int32, iconSet *IconSet, highlighted bool) {
+	if t, ok := GetRuleType(r.GetTag()); ok && t.Draw != nil {
+		t.Draw(r, x, y, iconSet, highlighted)
+		return
+	}
+	r.Draw(x, y, iconSet, highlighted)
+}
+
+// MarshalRuleJSON is a MarshalJSON implementation for rules which
+// implement json.Marshaler or have exported fields.
+func MarshalRuleJSON(r Ruler) ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// UnmarshalRuleJSON returns an UnmarshalJSON implementation which decodes
+// into a rule created by newRule.
+func UnmarshalRuleJSON(newRule func() Ruler) func([]byte) (Ruler, error) {
+	return func(bs []byte) (Ruler, error) {
+		r := newRule()
+		err := json.Unmarshal(bs, r)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+}
+
+type ruleJSON struct {
+	Type string          `json:"type"`
+	Rule json.RawMessage `json:"rule"`
+}
+
+func MarshalRulesJSON(rules Rules) ([]byte, error) {
+	out := make([]ruleJSON, 0, len(rules))
+	for _, r := range rules {
+		t, ok := GetRuleType(r.GetTag())
+		if !ok {
+			return nil, fmt.Errorf("unknown rule type: %q", r.GetTag())
+		}
+		bs, err := t.MarshalJSON(r)
+		if err != nil {
+			return nil, fmt.Errorf("marshal %q rule: %w", r.GetTag(), err)
+		}
+		out = append(out, ruleJSON{t.Tag, bs})
+	}
+	return json.Marshal(out)
+}
+
+func UnmarshalRulesJSON(bs []byte) (Rules, error) {
+	var in []ruleJSON
+	err := json.Unmarshal(bs, &in)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal rules: %w", err)
+	}
+
+	rules := make(Rules, 0, len(in))
+	for _, rj := range in {
+		t, ok := GetRuleType(rj.Type)
+		if !ok {
+			return nil, fmt.Errorf("unknown rule type: %q", rj.Type)
+		}
+		r, err := t.UnmarshalJSON(rj.Rule)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal %q rule: %w", rj.Type, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
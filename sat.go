@@ -0,0 +1,134 @@
+package goeinstein
+
+// SolveCNF is a minimal DPLL solver used to cross-check the CNF encoding
+// against the built-in solver. It returns the true literals of a model.
+func SolveCNF(c *CNF) ([]int, bool) {
+	assign := make([]int8, c.Vars+1)
+	if !dpll(c.Clauses, assign) {
+		return nil, false
+	}
+	var lits []int
+	for v := 1; v <= c.Vars; v++ {
+		if assign[v] > 0 {
+			lits = append(lits, v)
+		}
+	}
+	return lits, true
+}
+
+// IsUniqueCNF reports whether the CNF has exactly one model.
+func IsUniqueCNF(c *CNF) bool {
+	lits, ok := SolveCNF(c)
+	if !ok {
+		return false
+	}
+	block := make([]int, 0, len(lits))
+	for _, lit := range lits {
+		block = append(block, -lit)
+	}
+	other := *c
+	other.Clauses = append(append([][]int(nil), c.Clauses...), block)
+	_, ok = SolveCNF(&other)
+	return !ok
+}
+
+func litValue(assign []int8, lit int) int8 {
+	if lit > 0 {
+		return assign[lit]
+	}
+	return -assign[-lit]
+}
+
+func setLit(assign []int8, lit int) {
+	if lit > 0 {
+		assign[lit] = 1
+	} else {
+		assign[-lit] = -1
+	}
+}
+
+func dpll(clauses [][]int, assign []int8) bool {
+	// unit propagation
+	var trail []int
+	undo := func() {
+		for _, v := range trail {
+			assign[v] = 0
+		}
+	}
+	for {
+		var unit int
+		for _, cl := range clauses {
+			var free, freeLit int
+			var sat bool
+			for _, lit := range cl {
+				switch litValue(assign, lit) {
+				case 1:
+					sat = true
+				case 0:
+					free++
+					freeLit = lit
+				}
+				if sat {
+					break
+				}
+			}
+			if sat {
+				continue
+			}
+			if free == 0 {
+				undo()
+				return false
+			}
+			if free == 1 {
+				unit = freeLit
+				break
+			}
+		}
+		if unit == 0 {
+			break
+		}
+		setLit(assign, unit)
+		if unit < 0 {
+			unit = -unit
+		}
+		trail = append(trail, unit)
+	}
+
+	// branch on the first unassigned variable of an unsatisfied clause
+	var branch int
+	for _, cl := range clauses {
+		var sat bool
+		var free int
+		for _, lit := range cl {
+			switch litValue(assign, lit) {
+			case 1:
+				sat = true
+			case 0:
+				if free == 0 {
+					free = lit
+				}
+			}
+		}
+		if !sat && free != 0 {
+			branch = free
+			break
+		}
+	}
+	if branch == 0 {
+		return true
+	}
+
+	for _, lit := range []int{branch, -branch} {
+		setLit(assign, lit)
+		if dpll(clauses, assign) {
+			return true
+		}
+		if lit > 0 {
+			assign[lit] = 0
+		} else {
+			assign[-lit] = 0
+		}
+	}
+	undo()
+	return false
+}
@@ -0,0 +1,160 @@
+package goeinstein
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func genTestPuzzle(t *testing.T, seed int64) (*SolvedPuzzle, Rules) {
+	t.Helper()
+	var puzzle SolvedPuzzle
+	var rules Rules
+	_, err := GenPuzzleConfig(&puzzle, &rules, rand.New(rand.NewSource(seed)), NewGenConfig())
+	if err != nil {
+		t.Fatalf("seed %d: %v", seed, err)
+	}
+	return &puzzle, rules
+}
+
+func TestCNFAgreesWithCanSolve(t *testing.T) {
+	for seed := int64(1); seed <= 10; seed++ {
+		puzzle, rules := genTestPuzzle(t, seed)
+		cnf, err := EncodeCNF(rules, PUZZLE_SIZE)
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		if !IsUniqueCNF(cnf) {
+			t.Errorf("seed %d: CNF of a solvable puzzle has more than one solution", seed)
+		}
+
+		// without a rule the puzzle may become unsolvable, but the solver
+		// must not solve what has several solutions
+		for i := range rules {
+			rest := append(append(Rules(nil), rules[:i]...), rules[i+1:]...)
+			cnf, err := EncodeCNF(rest, PUZZLE_SIZE)
+			if err != nil {
+				t.Fatalf("seed %d: %v", seed, err)
+			}
+			if CanSolve(puzzle, &rest) && !IsUniqueCNF(cnf) {
+				t.Errorf("seed %d without rule %d: solved, but CNF has more than one solution", seed, i)
+			}
+		}
+	}
+}
+
+func TestDecodeCNFSolution(t *testing.T) {
+	for seed := int64(1); seed <= 10; seed++ {
+		puzzle, rules := genTestPuzzle(t, seed)
+		cnf, err := EncodeCNF(rules, PUZZLE_SIZE)
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		lits, ok := SolveCNF(cnf)
+		if !ok {
+			t.Fatalf("seed %d: CNF is unsatisfiable", seed)
+		}
+		solved, err := DecodeCNFSolution(lits)
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		if *solved != *puzzle {
+			t.Errorf("seed %d: decoded %v, want %v", seed, *solved, *puzzle)
+		}
+	}
+}
+
+func TestDIMACSRoundTrip(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		puzzle, rules := genTestPuzzle(t, seed)
+		cnf, err := EncodeCNF(rules, PUZZLE_SIZE)
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		var buf bytes.Buffer
+		err = cnf.WriteDIMACS(&buf)
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		read, err := readDIMACS(&buf)
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		if read.Vars != cnf.Vars || len(read.Clauses) != len(cnf.Clauses) {
+			t.Fatalf("seed %d: read %d vars and %d clauses, want %d and %d",
+				seed, read.Vars, len(read.Clauses), cnf.Vars, len(cnf.Clauses))
+		}
+
+		lits, ok := SolveCNF(read)
+		if !ok {
+			t.Fatalf("seed %d: CNF is unsatisfiable", seed)
+		}
+		for _, answer := range []string{
+			"s SATISFIABLE\nv " + joinLits(lits) + " 0\n",
+			"SAT\n" + joinLits(lits) + " 0\n",
+		} {
+			solved, err := ReadDIMACSSolution(strings.NewReader(answer))
+			if err != nil {
+				t.Fatalf("seed %d: %v", seed, err)
+			}
+			if *solved != *puzzle {
+				t.Errorf("seed %d: read %v, want %v", seed, *solved, *puzzle)
+			}
+		}
+	}
+
+	_, err := ReadDIMACSSolution(strings.NewReader("s UNSATISFIABLE\n"))
+	if err == nil {
+		t.Error("unsatisfiable answer is read without an error")
+	}
+}
+
+// readDIMACS parses the output of WriteDIMACS.
+func readDIMACS(r io.Reader) (*CNF, error) {
+	c := &CNF{}
+	scanner := bufio.NewScanner(r)
+	var cl []int
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] == "c" {
+			continue
+		}
+		if fields[0] == "p" {
+			if len(fields) != 4 || fields[1] != "cnf" {
+				return nil, fmt.Errorf("wrong header %q", scanner.Text())
+			}
+			vars, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, err
+			}
+			c.Vars = vars
+			continue
+		}
+		for _, f := range fields {
+			lit, err := strconv.Atoi(f)
+			if err != nil {
+				return nil, err
+			}
+			if lit == 0 {
+				c.Clauses = append(c.Clauses, cl)
+				cl = nil
+				continue
+			}
+			cl = append(cl, lit)
+		}
+	}
+	return c, scanner.Err()
+}
+
+func joinLits(lits []int) string {
+	s := make([]string, len(lits))
+	for i, lit := range lits {
+		s[i] = strconv.Itoa(lit)
+	}
+	return strings.Join(s, " ")
+}
@@ -0,0 +1,236 @@
+package goeinstein
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// Save file layout:
+//
+//	magic   [8]byte "GOEINSAV"
+//	version int32
+//	length  int32   payload length
+//	crc     int32   CRC-32 (IEEE) of the payload
+//	payload         name string + SaveInfo.Save + Game.Save (with MoveLog)
+//
+// Files written before the header was introduced have no magic and consist
+// of the payload only. They are treated as version 0.
+//
+//nolint:golint,nosnakecase,stylecheck
+const (
+	SAVE_MAGIC   = "GOEINSAV"
+	SAVE_VERSION = 7
+)
+
+var ErrSaveChecksum = errors.New("save file checksum mismatch")
+
+// SaveMigration converts the payload of a save file from the version it is
+// registered for to the next one.
+type SaveMigration func(payload []byte) ([]byte, error)
+
+// saveMigrations[v] converts payload of version v to version v+1.
+var saveMigrations = []SaveMigration{
+	// 0 -> 1: the header was added, the payload is unchanged.
+	func(payload []byte) ([]byte, error) { return payload, nil },
+	// 1 -> 2: save info was added after the name.
+	migrateSaveInfo,
+	// 2 -> 3: the hinted flag was added to save info. Old games are treated
+	// as hinted, as they were loaded before.
+	migrateSaveInfoHinted,
+	// 3 -> 4: the move log was added after the watch. Moves of old games are
+	// unknown, so their scores cannot be verified.
+	func(payload []byte) ([]byte, error) {
+		var buf bytes.Buffer
+		buf.Write(payload)
+		NewMoveLog(false).Save(&buf)
+		return buf.Bytes(), nil
+	},
+	// 4 -> 5: the undo history was added after the move log.
+	func(payload []byte) ([]byte, error) {
+		var buf bytes.Buffer
+		buf.Write(payload)
+		NewUndoHistory().Save(&buf)
+		return buf.Bytes(), nil
+	},
+	// 5 -> 6: undo and redo are written to the move log instead of removing
+	// the undone moves from it. The old history does not match the log, so it
+	// is dropped.
+	func(payload []byte) ([]byte, error) {
+		stream := bytes.NewReader(payload)
+		if _, err := skipSaveMoves(stream, newSaveInfoStreamV3); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		buf.Write(payload[:len(payload)-stream.Len()])
+		NewUndoHistory().Save(&buf)
+		return buf.Bytes(), nil
+	},
+	// 6 -> 7: the start time of the game was added to save info. It is
+	// unknown for old games, so they get a new record in the history.
+	func(payload []byte) ([]byte, error) {
+		stream := bytes.NewReader(payload)
+		name, err := ReadString(stream)
+		if err != nil {
+			return nil, err
+		}
+		info, err := newSaveInfoStreamV3(stream)
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		WriteString(&buf, name)
+		info.Save(&buf)
+		buf.Write(payload[len(payload)-stream.Len():])
+		return buf.Bytes(), nil
+	},
+}
+
+func migrateSaveInfoHinted(payload []byte) ([]byte, error) {
+	stream := bytes.NewReader(payload)
+	name, err := ReadString(stream)
+	if err != nil {
+		return nil, err
+	}
+	info, err := newSaveInfoStreamV2(stream)
+	if err != nil {
+		return nil, err
+	}
+	info.Hinted = true
+
+	var buf bytes.Buffer
+	WriteString(&buf, name)
+	info.saveV3(&buf)
+	buf.Write(payload[len(payload)-stream.Len():])
+	return buf.Bytes(), nil
+}
+
+// migrateSaveInfo fills what can be restored without rendering: the elapsed
+// time and the solved cells.
+func migrateSaveInfo(payload []byte) ([]byte, error) {
+	stream := bytes.NewReader(payload)
+	name, err := ReadString(stream)
+	if err != nil {
+		return nil, err
+	}
+	game := payload[len(payload)-stream.Len():]
+
+	info := &SaveInfo{Size: PUZZLE_SIZE}
+	var puzzle SolvedPuzzle
+	var rules Rules
+	if LoadPuzzle(&puzzle, stream) == nil && LoadRules(&rules, stream) == nil {
+		if possib, err := NewPossibilitiesStream(stream); err == nil {
+			info.Solved = GetSolvedPercent(possib)
+		}
+	}
+	if len(game) >= 4 {
+		info.Elapsed, _ = ReadInt(bytes.NewReader(game[len(game)-4:]))
+	}
+
+	var buf bytes.Buffer
+	WriteString(&buf, name)
+	info.saveV2(&buf)
+	buf.Write(game)
+	return buf.Bytes(), nil
+}
+
+// WriteSaveFile writes the game with the header of the current version.
+func WriteSaveFile(w io.Writer, name string, game *Game) error {
+	var payload bytes.Buffer
+	WriteString(&payload, name)
+	NewSaveInfo(game).Save(&payload)
+	game.Save(&payload)
+	return writeSavePayload(w, payload.Bytes())
+}
+
+func writeSavePayload(w io.Writer, payload []byte) error {
+	var buf bytes.Buffer
+	buf.WriteString(SAVE_MAGIC)
+	WriteInt(&buf, SAVE_VERSION)
+	WriteInt(&buf, len(payload))
+	WriteInt(&buf, int(crc32.ChecksumIEEE(payload)))
+	buf.Write(payload)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// RenameSaveFile changes the name of the saved game stored in the file.
+// The file is rewritten in the current version.
+func RenameSaveFile(fileName, name string) error {
+	bs, err := os.ReadFile(fileName)
+	if err != nil {
+		return fmt.Errorf("read saved file (filename: %q): %w", fileName, err)
+	}
+	payload, err := ReadSavePayload(bs)
+	if err != nil {
+		return fmt.Errorf("read saved file (filename: %q): %w", fileName, err)
+	}
+	stream := bytes.NewReader(payload)
+	_, err = ReadString(stream)
+	if err != nil {
+		return fmt.Errorf("read saved game name (filename: %q): %w", fileName, err)
+	}
+
+	var renamed bytes.Buffer
+	WriteString(&renamed, name)
+	renamed.Write(payload[len(payload)-stream.Len():])
+
+	var out bytes.Buffer
+	err = writeSavePayload(&out, renamed.Bytes())
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(fileName, out.Bytes(), 0o644)
+}
+
+// ReadSavePayload checks the header of the save file and returns its payload
+// migrated to the current version.
+func ReadSavePayload(bs []byte) ([]byte, error) {
+	version, payload, err := splitSaveFile(bs)
+	if err != nil {
+		return nil, err
+	}
+	if version > SAVE_VERSION {
+		return nil, fmt.Errorf("save file version %d is newer than supported %d", version, SAVE_VERSION)
+	}
+	for ; version < SAVE_VERSION; version++ {
+		payload, err = saveMigrations[version](payload)
+		if err != nil {
+			return nil, fmt.Errorf("migrate save file from version %d: %w", version, err)
+		}
+	}
+	return payload, nil
+}
+
+func splitSaveFile(bs []byte) (int, []byte, error) {
+	if !bytes.HasPrefix(bs, []byte(SAVE_MAGIC)) {
+		return 0, bs, nil
+	}
+
+	stream := bytes.NewReader(bs[len(SAVE_MAGIC):])
+	version, err := ReadInt(stream)
+	if err != nil {
+		return 0, nil, fmt.Errorf("read save version: %w", err)
+	}
+	length, err := ReadInt(stream)
+	if err != nil {
+		return 0, nil, fmt.Errorf("read save length: %w", err)
+	}
+	crc, err := ReadInt(stream)
+	if err != nil {
+		return 0, nil, fmt.Errorf("read save checksum: %w", err)
+	}
+	if length < 0 || length != stream.Len() {
+		return 0, nil, fmt.Errorf("wrong save payload length: %d (file has %d)", length, stream.Len())
+	}
+	payload := bs[len(bs)-length:]
+	if uint32(crc) != crc32.ChecksumIEEE(payload) {
+		return 0, nil, ErrSaveChecksum
+	}
+	return version, payload, nil
+}
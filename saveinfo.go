@@ -0,0 +1,405 @@
+package goeinstein
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+//nolint:golint,nosnakecase,stylecheck
+const (
+	THUMBNAIL_CELL = 10
+	THUMBNAIL_GAP  = 1
+	THUMBNAIL_SIZE = PUZZLE_SIZE*(THUMBNAIL_CELL+THUMBNAIL_GAP) + THUMBNAIL_GAP
+	// MAX_THUMBNAIL_SIZE limits thumbnails read from save files.
+	MAX_THUMBNAIL_SIZE = 256
+)
+
+// Thumbnail is a small RGB picture of the board.
+type Thumbnail struct {
+	width, height int
+	pixels        []byte
+}
+
+// NewThumbnail draws the board: solved cells as downscaled icons, other
+// cells as gray squares which are lighter the less variants are left.
+func NewThumbnail(possib *Possibilities, iconSet *IconSet) *Thumbnail {
+	t := &Thumbnail{
+		width:  THUMBNAIL_SIZE,
+		height: THUMBNAIL_SIZE,
+		pixels: make([]byte, THUMBNAIL_SIZE*THUMBNAIL_SIZE*3),
+	}
+	for i := 0; i < len(t.pixels); i += 3 {
+		t.pixels[i+2] = 64
+	}
+
+	for row := 0; row < PUZZLE_SIZE; row++ {
+		for col := 0; col < PUZZLE_SIZE; col++ {
+			x := THUMBNAIL_GAP + col*(THUMBNAIL_CELL+THUMBNAIL_GAP)
+			y := THUMBNAIL_GAP + row*(THUMBNAIL_CELL+THUMBNAIL_GAP)
+			if card, ok := possib.GetDefined(col, row); ok {
+				t.drawIcon(x, y, iconSet.GetLargeIcon(row, card, false))
+				continue
+			}
+			var cnt int
+			for e := Card(1); e <= PUZZLE_SIZE; e++ {
+				if possib.IsPossible(col, row, e) {
+					cnt++
+				}
+			}
+			c := uint8(200 - cnt*20)
+			for j := 0; j < THUMBNAIL_CELL; j++ {
+				for i := 0; i < THUMBNAIL_CELL; i++ {
+					t.set(x+i, y+j, c, c, c)
+				}
+			}
+		}
+	}
+	return t
+}
+
+func (t *Thumbnail) set(x, y int, r, g, b uint8) {
+	p := t.pixels[(y*t.width+x)*3:]
+	p[0], p[1], p[2] = r, g, b
+}
+
+// drawIcon downscales the icon into a cell averaging blocks of pixels.
+func (t *Thumbnail) drawIcon(x, y int, icon *sdl.Surface) {
+	for j := 0; j < THUMBNAIL_CELL; j++ {
+		for i := 0; i < THUMBNAIL_CELL; i++ {
+			x0, x1 := int32(i)*icon.W/THUMBNAIL_CELL, int32(i+1)*icon.W/THUMBNAIL_CELL
+			y0, y1 := int32(j)*icon.H/THUMBNAIL_CELL, int32(j+1)*icon.H/THUMBNAIL_CELL
+			var sr, sg, sb, n int
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					r, g, b := GetPixel(icon, sx, sy)
+					sr += int(r)
+					sg += int(g)
+					sb += int(b)
+					n++
+				}
+			}
+			if n > 0 {
+				t.set(x+i, y+j, uint8(sr/n), uint8(sg/n), uint8(sb/n))
+			}
+		}
+	}
+}
+
+func (t *Thumbnail) Save(stream io.Writer) {
+	WriteInt(stream, t.width)
+	WriteInt(stream, t.height)
+	_, err := stream.Write(t.pixels)
+	if err != nil {
+		panic(fmt.Errorf("write thumbnail: %w", err))
+	}
+}
+
+// NewThumbnailStream reads a thumbnail. It returns nil if the thumbnail is
+// empty.
+func NewThumbnailStream(stream io.Reader) (*Thumbnail, error) {
+	t := &Thumbnail{}
+	var err error
+	t.width, err = ReadInt(stream)
+	if err != nil {
+		return nil, err
+	}
+	t.height, err = ReadInt(stream)
+	if err != nil {
+		return nil, err
+	}
+	if t.width < 0 || t.width > MAX_THUMBNAIL_SIZE || t.height < 0 || t.height > MAX_THUMBNAIL_SIZE {
+		return nil, fmt.Errorf("wrong thumbnail size: %dx%d", t.width, t.height)
+	}
+	t.pixels = make([]byte, t.width*t.height*3)
+	_, err = io.ReadFull(stream, t.pixels)
+	if err != nil {
+		return nil, fmt.Errorf("read thumbnail: %w", err)
+	}
+	if t.width == 0 || t.height == 0 {
+		return nil, nil
+	}
+	return t, nil
+}
+
+// NewSurface returns the thumbnail in the screen format.
+func (t *Thumbnail) NewSurface() *sdl.Surface {
+	f := screen.GetSurface().Format
+	s := SDL_CreateRGBSurface(sdl.SWSURFACE, int32(t.width), int32(t.height), int32(f.BitsPerPixel), f.Rmask, f.Gmask, f.Bmask, f.Amask)
+	SDL_LockSurface(s)
+	for y := 0; y < t.height; y++ {
+		for x := 0; x < t.width; x++ {
+			p := t.pixels[(y*t.width+x)*3:]
+			_ = SetPixel(s, int32(x), int32(y), p[0], p[1], p[2])
+		}
+	}
+	SDL_UnlockSurface(s)
+	return s
+}
+
+// SaveInfo describes a saved game for the Load and Save windows.
+// Zero fields are unknown, e.g. in saves converted from old versions.
+type SaveInfo struct {
+	Time       time.Time
+	Elapsed    int // milliseconds
+	Solved     int // percent of solved cells
+	Difficulty string
+	Size       int
+	Seed       int64
+	Thumbnail  *Thumbnail
+	// Hinted is true if the game cannot get into the hall of fame.
+	Hinted bool
+	// Started is when the puzzle was generated. With the seed it tells the
+	// game in the history.
+	Started time.Time
+}
+
+func NewSaveInfo(g *Game) *SaveInfo {
+	return &SaveInfo{
+		Time:       time.Now(),
+		Elapsed:    g.watch.GetElapsed(),
+		Solved:     GetSolvedPercent(g.possibilities),
+		Difficulty: g.GetDifficulty(),
+		Size:       PUZZLE_SIZE,
+		Seed:       g.GetSeed(),
+		Thumbnail:  NewThumbnail(g.possibilities, g.iconSet),
+		Hinted:     g.IsHinted(),
+		Started:    g.started,
+	}
+}
+
+// GetSolvedPercent returns the percent of cells with the only variant left.
+func GetSolvedPercent(p *Possibilities) int {
+	var solved int
+	for row := 0; row < PUZZLE_SIZE; row++ {
+		for col := 0; col < PUZZLE_SIZE; col++ {
+			if p.IsDefined(col, row) {
+				solved++
+			}
+		}
+	}
+	return solved * 100 / (PUZZLE_SIZE * PUZZLE_SIZE)
+}
+
+func (i *SaveInfo) Save(stream io.Writer) {
+	i.saveV3(stream)
+	var unix int64
+	if !i.Started.IsZero() {
+		unix = i.Started.Unix()
+	}
+	WriteInt64(stream, unix)
+}
+
+// saveV3 writes the info as it was stored in versions 3 to 6 of save files.
+func (i *SaveInfo) saveV3(stream io.Writer) {
+	i.saveV2(stream)
+	WriteInt(stream, boolToInt[i.Hinted])
+}
+
+// saveV2 writes the info as it was stored in version 2 of save files.
+func (i *SaveInfo) saveV2(stream io.Writer) {
+	var unix int64
+	if !i.Time.IsZero() {
+		unix = i.Time.Unix()
+	}
+	WriteInt64(stream, unix)
+	WriteInt(stream, i.Elapsed)
+	WriteInt(stream, i.Solved)
+	WriteString(stream, i.Difficulty)
+	WriteInt(stream, i.Size)
+	WriteInt64(stream, i.Seed)
+	if i.Thumbnail != nil {
+		i.Thumbnail.Save(stream)
+	} else {
+		WriteInt(stream, 0)
+		WriteInt(stream, 0)
+	}
+}
+
+func NewSaveInfoStream(stream io.Reader) (*SaveInfo, error) {
+	i, err := newSaveInfoStreamV3(stream)
+	if err != nil {
+		return nil, err
+	}
+	unix, err := ReadInt64(stream)
+	if err != nil {
+		return nil, err
+	}
+	if unix != 0 {
+		i.Started = time.Unix(unix, 0)
+	}
+	return i, nil
+}
+
+// newSaveInfoStreamV3 reads the info as it was stored in versions 3 to 6 of
+// save files.
+func newSaveInfoStreamV3(stream io.Reader) (*SaveInfo, error) {
+	i, err := newSaveInfoStreamV2(stream)
+	if err != nil {
+		return nil, err
+	}
+	hinted, err := ReadInt(stream)
+	if err != nil {
+		return nil, err
+	}
+	i.Hinted = hinted > 0
+	return i, nil
+}
+
+// newSaveInfoStreamV2 reads the info as it was stored in version 2 of save
+// files.
+func newSaveInfoStreamV2(stream io.Reader) (*SaveInfo, error) {
+	i := &SaveInfo{}
+	unix, err := ReadInt64(stream)
+	if err != nil {
+		return nil, err
+	}
+	if unix != 0 {
+		i.Time = time.Unix(unix, 0)
+	}
+	i.Elapsed, err = ReadInt(stream)
+	if err != nil {
+		return nil, err
+	}
+	i.Solved, err = ReadInt(stream)
+	if err != nil {
+		return nil, err
+	}
+	i.Difficulty, err = ReadString(stream)
+	if err != nil {
+		return nil, err
+	}
+	i.Size, err = ReadInt(stream)
+	if err != nil {
+		return nil, err
+	}
+	i.Seed, err = ReadInt64(stream)
+	if err != nil {
+		return nil, err
+	}
+	i.Thumbnail, err = NewThumbnailStream(stream)
+	if err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// SaveInfoPanel shows the info of the saved game under the mouse.
+type SaveInfoPanel struct {
+	Widget
+
+	left, top, width, height int32
+	list                     []*SavedGame
+	slotLeft, slotTop        int32
+	slotWidth, slotStep      int32
+	font                     *Font
+	current                  int
+	thumbnail                *sdl.Surface
+}
+
+var _ AreaWidgeter = (*SaveInfoPanel)(nil)
+
+func NewSaveInfoPanel(x, y, w, h int32, font *Font, list []*SavedGame, slotLeft, slotTop, slotWidth, slotStep int32) *SaveInfoPanel {
+	return &SaveInfoPanel{
+		left:      x,
+		top:       y,
+		width:     w,
+		height:    h,
+		list:      list,
+		slotLeft:  slotLeft,
+		slotTop:   slotTop,
+		slotWidth: slotWidth,
+		slotStep:  slotStep,
+		font:      font,
+		current:   -1,
+	}
+}
+
+func (p *SaveInfoPanel) Close() {
+	p.setThumbnail(nil)
+}
+
+func (p *SaveInfoPanel) setThumbnail(t *Thumbnail) {
+	if p.thumbnail != nil {
+		SDL_FreeSurface(p.thumbnail)
+		p.thumbnail = nil
+	}
+	if t != nil {
+		p.thumbnail = t.NewSurface()
+	}
+}
+
+func (p *SaveInfoPanel) OnMouseMove(x, y int32) bool {
+	no := -1
+	if x >= p.slotLeft && x < p.slotLeft+p.slotWidth && y >= p.slotTop {
+		no = int((y - p.slotTop) / p.slotStep)
+		if no >= len(p.list) {
+			no = -1
+		}
+	}
+	if no != p.current {
+		p.current = no
+		var t *Thumbnail
+		if info := p.getInfo(); info != nil {
+			t = info.Thumbnail
+		}
+		p.setThumbnail(t)
+		p.Draw()
+	}
+	return false
+}
+
+func (p *SaveInfoPanel) getInfo() *SaveInfo {
+	if p.current < 0 || p.list[p.current].GetError() != nil {
+		return nil
+	}
+	return p.list[p.current].GetInfo()
+}
+
+func (p *SaveInfoPanel) Draw() {
+	SDL_FillRect(screen.GetSurface(), &sdl.Rect{X: p.left, Y: p.top, W: p.width, H: p.height}, sdl.MapRGB(screen.GetSurface().Format, 0, 0, 64))
+	screen.AddRegionToUpdate(p.left, p.top, p.width, p.height)
+
+	info := p.getInfo()
+	if info == nil {
+		return
+	}
+
+	y := p.top + 10
+	if p.thumbnail != nil {
+		screen.Draw(p.left+(p.width-p.thumbnail.W)/2, y, p.thumbnail)
+		y += p.thumbnail.H + 10
+	}
+
+	unknown := "-"
+	date := unknown
+	if !info.Time.IsZero() {
+		date = info.Time.Format("2006-01-02 15:04")
+	}
+	difficulty := unknown
+	if info.Difficulty != "" {
+		difficulty = msg(info.Difficulty)
+	}
+	size := unknown
+	if info.Size > 0 {
+		size = fmt.Sprintf("%dx%d", info.Size, info.Size)
+	}
+	seed := unknown
+	if info.Seed != 0 {
+		seed = ToString(info.Seed)
+	}
+	lines := [][2]string{
+		{msg("saveDate"), date},
+		{msg("saveElapsed"), SecToStr(uint64(info.Elapsed / 1000))},
+		{msg("saveSolved"), fmt.Sprintf("%d%%", info.Solved)},
+		{msg("saveDifficulty"), difficulty + ", " + size},
+		{msg("saveSeed"), seed},
+	}
+	for _, l := range lines {
+		p.font.Draw(p.left+10, y, 255, 255, 0, true, l[0])
+		p.font.Draw(p.left+100, y, 255, 255, 255, true, l[1])
+		y += 20
+	}
+}
@@ -0,0 +1,155 @@
+package goeinstein
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+//nolint:golint,nosnakecase,stylecheck
+const INSTALL_KEY_SIZE = 32
+
+var (
+	ErrNotSigned    = errors.New("score is not signed")
+	ErrBadSignature = errors.New("score signature mismatch")
+)
+
+// installKey signs the hall of fame entries of this installation.
+var installKey []byte
+
+func GetInstallKeyFileName() string {
+	return filepath.Join(GetConfigDir(), "install.key")
+}
+
+// GetInstallKey returns the key of this installation, it is created on the
+// first use.
+func GetInstallKey() ([]byte, error) {
+	if installKey != nil {
+		return installKey, nil
+	}
+	fileName := GetInstallKeyFileName()
+	key, err := os.ReadFile(fileName)
+	if err == nil && len(key) == INSTALL_KEY_SIZE {
+		installKey = key
+		return key, nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read install key: %w", err)
+	}
+	key = make([]byte, INSTALL_KEY_SIZE)
+	if _, err = rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generate install key: %w", err)
+	}
+	if err = WriteFileAtomic(fileName, key, 0o600); err != nil {
+		return nil, fmt.Errorf("write install key: %w", err)
+	}
+	installKey = key
+	return key, nil
+}
+
+func (e *TopScoreEntry) signature(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%q %d %d %d %q %d %q %t %q\n",
+		e.Name, e.Time, e.Date.Unix(), e.Seed, e.Difficulty, e.Size, e.Day, e.Hinted, e.Profile)
+	mac.Write(e.Moves)
+	return mac.Sum(nil)
+}
+
+// Sign signs the entry with the install key. It must be called after all
+// fields are set.
+func (e *TopScoreEntry) Sign() error {
+	key, err := GetInstallKey()
+	if err != nil {
+		return err
+	}
+	e.Signature = hex.EncodeToString(e.signature(key))
+	return nil
+}
+
+// scoreCheck is the replay of a signed entry in the background.
+type scoreCheck struct {
+	done chan struct{}
+	err  error
+}
+
+// scoreChecks keeps the replays by signature for the run of the game, so an
+// entry is replayed once even if the hall of fame is read again. Only entries
+// with a valid signature are kept, so the signature tells the entry.
+var scoreChecks = struct {
+	sync.Mutex
+	checks map[string]*scoreCheck
+}{checks: make(map[string]*scoreCheck)}
+
+func newFailedScoreCheck(err error) *scoreCheck {
+	c := &scoreCheck{done: make(chan struct{}), err: err}
+	close(c.done)
+	return c
+}
+
+// check checks the signature and starts the replay of the moves if it is not
+// started yet. It is called on the main thread.
+func (e *TopScoreEntry) check() *scoreCheck {
+	if e.Signature == "" {
+		return newFailedScoreCheck(ErrNotSigned)
+	}
+	key, err := GetInstallKey()
+	if err != nil {
+		return newFailedScoreCheck(err)
+	}
+	sig, err := hex.DecodeString(e.Signature)
+	if err != nil || !hmac.Equal(sig, e.signature(key)) {
+		return newFailedScoreCheck(ErrBadSignature)
+	}
+
+	scoreChecks.Lock()
+	defer scoreChecks.Unlock()
+	c := scoreChecks.checks[e.Signature]
+	if c != nil {
+		return c
+	}
+	c = &scoreCheck{done: make(chan struct{})}
+	scoreChecks.checks[e.Signature] = c
+	entry := *e
+	go func() {
+		c.err = entry.replay()
+		close(c.done)
+	}()
+	return c
+}
+
+// Verify checks the signature and replays the moves. Replays are done once,
+// the result is kept.
+func (e *TopScoreEntry) Verify() error {
+	c := e.check()
+	<-c.done
+	return c.err
+}
+
+// GetVerified returns the result of Verify without waiting for the replay.
+// done is false while the moves are replayed in the background.
+func (e *TopScoreEntry) GetVerified() (done bool, err error) {
+	c := e.check()
+	select {
+	case <-c.done:
+		return true, c.err
+	default:
+		return false, nil
+	}
+}
+
+func (e *TopScoreEntry) replay() error {
+	l, err := DecodeMoveLog(e.Moves)
+	if err != nil {
+		return err
+	}
+	if l.IsHinted() && !e.Hinted {
+		return errors.New("hints were used")
+	}
+	return VerifyMoveLog(l, e.Seed, e.Difficulty, e.Time)
+}
@@ -0,0 +1,450 @@
+package goeinstein
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Setting is a typed value kept in the storage under a namespaced key like
+// "screen.fullscreen". The options window edits a copy of the value which
+// is applied on Ok and reverted on Cancel.
+type Setting interface {
+	GetKey() string
+	// GetTitle returns the message key of the label.
+	GetTitle() string
+	Load(storage Storage)
+	Save(storage Storage)
+	// Apply makes the edited value current and calls the change callback.
+	// It returns true if the value was changed.
+	Apply() bool
+	Revert()
+	String() string
+	// SetLegacyKey sets the key used before namespaced keys. It is read if
+	// the key is not in the storage.
+	SetLegacyKey(key string)
+	// AddEditor adds widgets to edit the value at (x, y) of w x h.
+	AddEditor(area *Area, font *Font, x, y, w, h int32)
+}
+
+type settingBase struct {
+	key, title string
+	legacyKey  string
+}
+
+func (s *settingBase) GetKey() string   { return s.key }
+func (s *settingBase) GetTitle() string { return s.title }
+
+// storageKey returns the key to read the value from.
+func (s *settingBase) storageKey(storage Storage) string {
+	if s.legacyKey != "" && !storage.HasKey(s.key) && storage.HasKey(s.legacyKey) {
+		return s.legacyKey
+	}
+	return s.key
+}
+
+func (s *settingBase) SetLegacyKey(key string) { s.legacyKey = key }
+
+type BoolSetting struct {
+	settingBase
+	dflt, value, edit bool
+	onChange          func(bool)
+}
+
+var _ Setting = (*BoolSetting)(nil)
+
+func NewBoolSetting(key, title string, dflt bool, onChange func(bool)) *BoolSetting {
+	s := &BoolSetting{dflt: dflt, value: dflt, edit: dflt, onChange: onChange}
+	s.key = key
+	s.title = title
+	return s
+}
+
+func (s *BoolSetting) Value() bool { return s.value }
+
+func (s *BoolSetting) Load(storage Storage) {
+	s.value = storage.GetBool(s.storageKey(storage), s.dflt)
+	s.edit = s.value
+}
+
+func (s *BoolSetting) Save(storage Storage) { storage.SetBool(s.key, s.value) }
+
+func (s *BoolSetting) Apply() bool {
+	if s.edit == s.value {
+		return false
+	}
+	s.value = s.edit
+	if s.onChange != nil {
+		s.onChange(s.value)
+	}
+	return true
+}
+
+func (s *BoolSetting) Revert()        { s.edit = s.value }
+func (s *BoolSetting) String() string { return ToString(s.value) }
+
+func (s *BoolSetting) AddEditor(area *Area, font *Font, x, y, w, h int32) {
+	area.Add(NewCheckbox(x, y, h, h, font, 255, 255, 255, "blue.bmp", &s.edit))
+}
+
+// IntSetting is an int of [min, max] edited with a slider.
+type IntSetting struct {
+	settingBase
+	min, max          int
+	dflt, value, edit int
+	pos               float32
+	onChange, preview func(int)
+}
+
+var _ Setting = (*IntSetting)(nil)
+
+// NewIntSetting creates the setting. preview is called while the slider is
+// moved and on revert, it may be nil.
+func NewIntSetting(key, title string, min, max, dflt int, onChange, preview func(int)) *IntSetting {
+	if min >= max || dflt < min || dflt > max {
+		panic(fmt.Errorf("wrong range of %q: %d <= %d <= %d", key, min, dflt, max))
+	}
+	s := &IntSetting{min: min, max: max, dflt: dflt, value: dflt, edit: dflt, onChange: onChange, preview: preview}
+	s.key = key
+	s.title = title
+	return s
+}
+
+func (s *IntSetting) Value() int { return s.value }
+
+func (s *IntSetting) clamp(v int) int {
+	if v < s.min {
+		return s.min
+	}
+	if v > s.max {
+		return s.max
+	}
+	return v
+}
+
+func (s *IntSetting) Load(storage Storage) {
+	s.value = s.clamp(storage.GetInt(s.storageKey(storage), s.dflt))
+	s.edit = s.value
+}
+
+func (s *IntSetting) Save(storage Storage) { storage.SetInt(s.key, s.value) }
+
+func (s *IntSetting) Apply() bool {
+	s.edit = s.clamp(s.edit)
+	if s.edit == s.value {
+		return false
+	}
+	s.value = s.edit
+	if s.onChange != nil {
+		s.onChange(s.value)
+	}
+	return true
+}
+
+func (s *IntSetting) Revert() {
+	if s.edit != s.value && s.preview != nil {
+		s.preview(s.value)
+	}
+	s.edit = s.value
+}
+
+func (s *IntSetting) String() string { return ToString(s.value) }
+
+func (s *IntSetting) AddEditor(area *Area, font *Font, x, y, w, h int32) {
+	s.pos = float32(s.edit-s.min) / float32(s.max-s.min)
+	area.Add(NewSliderCmd(x, y+2, w, h-4, &s.pos, func(pos float32) {
+		s.edit = s.min + int(math.Round(float64(pos)*float64(s.max-s.min)))
+		if s.preview != nil {
+			s.preview(s.edit)
+		}
+	}))
+}
+
+// FloatSetting is a float of [min, max] edited with a slider.
+type FloatSetting struct {
+	settingBase
+	min, max          float32
+	dflt, value, edit float32
+	pos               float32
+	onChange, preview func(float32)
+}
+
+var _ Setting = (*FloatSetting)(nil)
+
+func NewFloatSetting(key, title string, min, max, dflt float32, onChange, preview func(float32)) *FloatSetting {
+	if min >= max || dflt < min || dflt > max {
+		panic(fmt.Errorf("wrong range of %q: %v <= %v <= %v", key, min, dflt, max))
+	}
+	s := &FloatSetting{min: min, max: max, dflt: dflt, value: dflt, edit: dflt, onChange: onChange, preview: preview}
+	s.key = key
+	s.title = title
+	return s
+}
+
+func (s *FloatSetting) Value() float32 { return s.value }
+
+func (s *FloatSetting) clamp(v float32) float32 {
+	if v < s.min || v != v {
+		return s.min
+	}
+	if v > s.max {
+		return s.max
+	}
+	return v
+}
+
+func (s *FloatSetting) Load(storage Storage) {
+	s.value = s.clamp(storage.GetFloat(s.storageKey(storage), s.dflt))
+	s.edit = s.value
+}
+
+func (s *FloatSetting) Save(storage Storage) { storage.SetFloat(s.key, s.value) }
+
+func (s *FloatSetting) Apply() bool {
+	s.edit = s.clamp(s.edit)
+	if s.edit == s.value {
+		return false
+	}
+	s.value = s.edit
+	if s.onChange != nil {
+		s.onChange(s.value)
+	}
+	return true
+}
+
+func (s *FloatSetting) Revert() {
+	if s.edit != s.value && s.preview != nil {
+		s.preview(s.value)
+	}
+	s.edit = s.value
+}
+
+func (s *FloatSetting) String() string { return ToString(s.value) }
+
+func (s *FloatSetting) AddEditor(area *Area, font *Font, x, y, w, h int32) {
+	s.pos = (s.edit - s.min) / (s.max - s.min)
+	area.Add(NewSliderCmd(x, y+2, w, h-4, &s.pos, func(pos float32) {
+		s.edit = s.min + pos*(s.max-s.min)
+		if s.preview != nil {
+			s.preview(s.edit)
+		}
+	}))
+}
+
+// EnumSetting is one of the values. The values are message keys.
+type EnumSetting struct {
+	settingBase
+	values            []string
+	dflt, value, edit string
+	onChange          func(string)
+}
+
+var _ Setting = (*EnumSetting)(nil)
+
+func NewEnumSetting(key, title string, values []string, dflt string, onChange func(string)) *EnumSetting {
+	s := &EnumSetting{values: values, dflt: dflt, value: dflt, edit: dflt, onChange: onChange}
+	if !s.isValid(dflt) {
+		panic(fmt.Errorf("wrong default of %q: %q", key, dflt))
+	}
+	s.key = key
+	s.title = title
+	return s
+}
+
+func (s *EnumSetting) Value() string { return s.value }
+
+func (s *EnumSetting) isValid(v string) bool {
+	for _, e := range s.values {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *EnumSetting) Load(storage Storage) {
+	s.value = storage.GetString(s.storageKey(storage), s.dflt)
+	if !s.isValid(s.value) {
+		s.value = s.dflt
+	}
+	s.edit = s.value
+}
+
+func (s *EnumSetting) Save(storage Storage) { storage.SetString(s.key, s.value) }
+
+func (s *EnumSetting) Apply() bool {
+	if !s.isValid(s.edit) {
+		s.edit = s.value
+	}
+	if s.edit == s.value {
+		return false
+	}
+	s.value = s.edit
+	if s.onChange != nil {
+		s.onChange(s.value)
+	}
+	return true
+}
+
+func (s *EnumSetting) Revert()        { s.edit = s.value }
+func (s *EnumSetting) String() string { return s.value }
+
+func (s *EnumSetting) AddEditor(area *Area, font *Font, x, y, w, h int32) {
+	area.Add(NewEnumSelector(x, y, w, h, font, s.values, &s.edit))
+}
+
+// StringSetting is a string of at most maxLen bytes.
+type StringSetting struct {
+	settingBase
+	maxLen            int
+	dflt, value, edit string
+	onChange          func(string)
+}
+
+var _ Setting = (*StringSetting)(nil)
+
+func NewStringSetting(key, title string, maxLen int, dflt string, onChange func(string)) *StringSetting {
+	s := &StringSetting{maxLen: maxLen, dflt: dflt, value: dflt, edit: dflt, onChange: onChange}
+	s.key = key
+	s.title = title
+	return s
+}
+
+func (s *StringSetting) Value() string { return s.value }
+
+func (s *StringSetting) Load(storage Storage) {
+	s.value = storage.GetString(s.storageKey(storage), s.dflt)
+	if len(s.value) > s.maxLen {
+		s.value = s.dflt
+	}
+	s.edit = s.value
+}
+
+func (s *StringSetting) Save(storage Storage) { storage.SetString(s.key, s.value) }
+
+func (s *StringSetting) Apply() bool {
+	s.edit = strings.TrimSpace(s.edit)
+	for len(s.edit) > s.maxLen {
+		// cut whole characters
+		_, size := utf8.DecodeLastRuneInString(s.edit)
+		s.edit = s.edit[:len(s.edit)-size]
+	}
+	if s.edit == s.value {
+		return false
+	}
+	s.value = s.edit
+	if s.onChange != nil {
+		s.onChange(s.value)
+	}
+	return true
+}
+
+func (s *StringSetting) Revert()        { s.edit = s.value }
+func (s *StringSetting) String() string { return s.value }
+
+func (s *StringSetting) AddEditor(area *Area, font *Font, x, y, w, h int32) {
+	area.Add(NewInputField(x, y, w, h, "blue.bmp", &s.edit, s.maxLen, 255, 255, 0, font))
+}
+
+// Settings is the registry of settings in the order of the options window.
+type Settings struct {
+	list  []Setting
+	byKey map[string]Setting
+}
+
+func NewSettings() *Settings {
+	return &Settings{
+		byKey: make(map[string]Setting),
+	}
+}
+
+func (s *Settings) Add(st Setting) {
+	if _, ok := s.byKey[st.GetKey()]; ok {
+		panic(fmt.Errorf("setting %q is already registered", st.GetKey()))
+	}
+	s.list = append(s.list, st)
+	s.byKey[st.GetKey()] = st
+}
+
+func (s *Settings) AddLegacy(st Setting, legacyKey string) {
+	st.SetLegacyKey(legacyKey)
+	s.Add(st)
+}
+
+func (s *Settings) Get(key string) (Setting, bool) {
+	st, ok := s.byKey[key]
+	return st, ok
+}
+
+func (s *Settings) GetAll() []Setting { return s.list }
+
+func (s *Settings) Load(storage Storage) {
+	for _, st := range s.list {
+		st.Load(storage)
+	}
+}
+
+// Apply applies edited values and saves changed ones to the storage.
+func (s *Settings) Apply(storage Storage) {
+	for _, st := range s.list {
+		if st.Apply() {
+			st.Save(storage)
+		}
+	}
+	storage.Flush()
+}
+
+func (s *Settings) Revert() {
+	for _, st := range s.list {
+		st.Revert()
+	}
+}
+
+// EnumSelector shows the message of the value, a click selects the next
+// value.
+type EnumSelector struct {
+	Window
+
+	font   *Font
+	values []string
+	value  *string
+}
+
+func NewEnumSelector(x, y, w, h int32, font *Font, values []string, value *string) *EnumSelector {
+	e := &EnumSelector{
+		Window: *NewWindowFrameRaised(x, y, w, h, "blue.bmp", 1, true),
+		font:   font,
+		values: values,
+		value:  value,
+	}
+	return e
+}
+
+func (e *EnumSelector) Draw() {
+	e.Window.Draw()
+	text := msg(*e.value)
+	tW, tH := e.font.GetSize(text)
+	e.font.Draw(e.left+(e.width-tW)/2, e.top+(e.height-tH)/2, 255, 255, 0, true, text)
+}
+
+func (e *EnumSelector) OnMouseButtonDown(button uint8, x, y int32) bool {
+	if !IsInRect(x, y, e.left, e.top, e.width, e.height) {
+		return false
+	}
+	sound.Play("click.wav")
+	next := 0
+	for i, v := range e.values {
+		if v == *e.value {
+			next = i + 1
+		}
+	}
+	if button == sdl.BUTTON_RIGHT {
+		next -= 2
+	}
+	*e.value = e.values[(next+len(e.values))%len(e.values)]
+	e.Draw()
+	return true
+}
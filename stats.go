@@ -0,0 +1,358 @@
+package goeinstein
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+//nolint:golint,nosnakecase,stylecheck
+const (
+	// HISTORY_VERSION is the version of the games history file.
+	HISTORY_VERSION = 1
+
+	GAME_WON       = "won"
+	GAME_FAILED    = "failed"
+	GAME_ABANDONED = "abandoned"
+)
+
+// GameRecord is a played game. A game which was left unfinished is recorded
+// as abandoned, its record is replaced when the game is finished or left
+// again. Games are told by the seed and the start time.
+type GameRecord struct {
+	Date       time.Time `json:"date"`
+	Result     string    `json:"result"`
+	Time       int       `json:"time"` // seconds
+	Difficulty string    `json:"difficulty"`
+	Size       int       `json:"size"`
+	Seed       int64     `json:"seed,omitempty"`
+	Mistakes   int       `json:"mistakes"`
+	Hinted     bool      `json:"hinted"`
+	Restarts   int       `json:"restarts"`
+	Daily      string    `json:"daily,omitempty"`
+	Started    int64     `json:"started,omitempty"` // unix time the puzzle was generated
+}
+
+func NewGameRecord(g *Game, result string) *GameRecord {
+	return &GameRecord{
+		Date:       time.Now(),
+		Result:     result,
+		Time:       g.watch.GetElapsed() / 1000,
+		Difficulty: g.GetDifficulty(),
+		Size:       PUZZLE_SIZE,
+		Seed:       g.GetSeed(),
+		Mistakes:   g.mistakes,
+		Hinted:     g.IsHinted(),
+		Restarts:   g.restarts,
+		Daily:      g.daily,
+		Started:    g.started.Unix(),
+	}
+}
+
+// IsGame reports whether the record is of the game g. Records written before
+// the start time was recorded are of no game.
+func (r *GameRecord) IsGame(g *Game) bool {
+	return r.Started != 0 && r.Started == g.started.Unix() && r.Seed == g.seed
+}
+
+type History struct {
+	Version int           `json:"version"`
+	Games   []*GameRecord `json:"games"`
+}
+
+func GetHistoryFileName() string {
+	return filepath.Join(GetProfileDataDir(), "history.json")
+}
+
+// LoadHistory reads the games history. Errors are logged and an empty
+// history is returned.
+func LoadHistory() *History {
+	h := &History{Version: HISTORY_VERSION}
+	bs, err := os.ReadFile(GetHistoryFileName())
+	if os.IsNotExist(err) {
+		return h
+	}
+	if err == nil {
+		err = json.Unmarshal(bs, h)
+	}
+	if err == nil && h.Version > HISTORY_VERSION {
+		err = fmt.Errorf("version %d is newer than supported %d", h.Version, HISTORY_VERSION)
+	}
+	if err != nil {
+		log.Printf("Error on read history (filename: %q): %v", GetHistoryFileName(), err)
+		return &History{Version: HISTORY_VERSION}
+	}
+	return h
+}
+
+func (h *History) Save() error {
+	h.Version = HISTORY_VERSION
+	bs, err := json.MarshalIndent(h, "", "\t")
+	if err != nil {
+		return fmt.Errorf("marshal history: %w", err)
+	}
+	return WriteFileAtomic(GetHistoryFileName(), bs, 0o664)
+}
+
+// FindAbandoned returns the index of the abandoned record of the game, or -1.
+func (h *History) FindAbandoned(g *Game) int {
+	for i := len(h.Games) - 1; i >= 0; i-- {
+		if h.Games[i].Result == GAME_ABANDONED && h.Games[i].IsGame(g) {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsFinished reports whether the game is recorded as won or failed.
+func (h *History) IsFinished(g *Game) bool {
+	for _, r := range h.Games {
+		if r.Result != GAME_ABANDONED && r.IsGame(g) {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordGame adds the game to the history replacing its abandoned record.
+// A game which is already finished, e.g. loaded from a save again, is not
+// recorded twice. Errors are only logged.
+func RecordGame(g *Game, result string) {
+	h := LoadHistory()
+	if h.IsFinished(g) {
+		return
+	}
+	if i := h.FindAbandoned(g); i >= 0 {
+		h.Games = append(h.Games[:i], h.Games[i+1:]...)
+	}
+	h.Games = append(h.Games, NewGameRecord(g, result))
+	if err := h.Save(); err != nil {
+		log.Printf("Error on save history: %v", err)
+	}
+}
+
+// ResumeGame takes the counters of the abandoned record of the loaded game,
+// so mistakes and restarts are not lost by loading the game. The record is
+// replaced when the game is recorded again.
+func ResumeGame(g *Game) {
+	h := LoadHistory()
+	i := h.FindAbandoned(g)
+	if i < 0 {
+		return
+	}
+	g.mistakes = h.Games[i].Mistakes
+	g.restarts = h.Games[i].Restarts
+}
+
+// GameStats are totals of the games of a difficulty.
+type GameStats struct {
+	Played    int
+	Won       int
+	TotalTime int
+	BestTime  int
+}
+
+func (s *GameStats) add(r *GameRecord) {
+	s.Played++
+	if r.Result != GAME_WON {
+		return
+	}
+	s.Won++
+	s.TotalTime += r.Time
+	if s.Won == 1 || r.Time < s.BestTime {
+		s.BestTime = r.Time
+	}
+}
+
+// GetWinRate returns the percent of won games.
+func (s *GameStats) GetWinRate() int {
+	if s.Played == 0 {
+		return 0
+	}
+	return s.Won * 100 / s.Played
+}
+
+func (s *GameStats) GetAverageTime() int {
+	if s.Won == 0 {
+		return 0
+	}
+	return s.TotalTime / s.Won
+}
+
+// GetStats returns the totals of the difficulty or of all games if it is "".
+func (h *History) GetStats(difficulty string) *GameStats {
+	s := &GameStats{}
+	for _, r := range h.Games {
+		if difficulty == "" || r.Difficulty == difficulty {
+			s.add(r)
+		}
+	}
+	return s
+}
+
+// GetStreaks returns the number of last won games in a row and the longest
+// such series.
+func (h *History) GetStreaks() (current, longest int) {
+	for _, r := range h.Games {
+		if r.Result == GAME_WON {
+			current++
+			if current > longest {
+				longest = current
+			}
+		} else {
+			current = 0
+		}
+	}
+	return current, longest
+}
+
+func (h *History) ExportJSON(fileName string) error {
+	bs, err := json.MarshalIndent(h.Games, "", "\t")
+	if err != nil {
+		return fmt.Errorf("marshal history: %w", err)
+	}
+	return WriteFileAtomic(fileName, bs, 0o664)
+}
+
+func (h *History) ExportCSV(fileName string) error {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{"date", "result", "time", "difficulty", "size", "seed", "mistakes", "hinted", "restarts", "daily", "started"})
+	for _, r := range h.Games {
+		_ = w.Write([]string{
+			r.Date.Format(time.RFC3339),
+			r.Result,
+			strconv.Itoa(r.Time),
+			r.Difficulty,
+			strconv.Itoa(r.Size),
+			strconv.FormatInt(r.Seed, 10),
+			strconv.Itoa(r.Mistakes),
+			strconv.FormatBool(r.Hinted),
+			strconv.Itoa(r.Restarts),
+			r.Daily,
+			startedCSV(r.Started),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("write csv: %w", err)
+	}
+	return WriteFileAtomic(fileName, buf.Bytes(), 0o664)
+}
+
+func startedCSV(unix int64) string {
+	if unix == 0 {
+		return ""
+	}
+	return time.Unix(unix, 0).Format(time.RFC3339)
+}
+
+type ExportHistoryCommand struct {
+	history    *History
+	format     string
+	font       *Font
+	parentArea *Area
+}
+
+var _ Command = (*ExportHistoryCommand)(nil)
+
+// NewExportHistoryCommand exports the history to the profile directory in the
+// format "csv" or "json".
+func NewExportHistoryCommand(h *History, format string, font *Font, parentArea *Area) *ExportHistoryCommand {
+	e := &ExportHistoryCommand{}
+	e.history = h
+	e.format = format
+	e.font = font
+	e.parentArea = parentArea
+	return e
+}
+
+func (e *ExportHistoryCommand) DoAction() {
+	fileName := filepath.Join(GetProfileDataDir(), "history."+e.format)
+	var err error
+	if e.format == "csv" {
+		err = e.history.ExportCSV(fileName)
+	} else {
+		err = e.history.ExportJSON(fileName)
+	}
+	text := msg("exported") + " " + fileName
+	if err != nil {
+		log.Printf("Error on export history: %v", err)
+		text = msg("exportError")
+	}
+	ShowMessageWindow(e.parentArea, "blue.bmp", 600, 80, e.font, 255, 255, 255, text)
+	e.parentArea.UpdateMouse()
+	e.parentArea.Draw()
+}
+
+// ShowStatisticsWindow shows totals per difficulty and the streaks.
+func ShowStatisticsWindow(parentArea *Area) {
+	h := LoadHistory()
+	area := NewArea()
+	titleFont := NewFont("nova.ttf", 26)
+	font := NewFont("laudcn2.ttf", 14)
+	btnFont := NewFont("laudcn2.ttf", 16)
+
+	area.Add(parentArea)
+	area.Add(NewWindow(100, 110, 600, 380, "blue.bmp"))
+	area.Add(NewLabelAligh(titleFont, 100, 120, 600, 40, ALIGN_CENTER, ALIGN_MIDDLE, 255, 255, 0,
+		msg("statistics")+": "+GetProfiles().GetCurrent().GetName()))
+
+	columns := []int32{120, 250, 330, 410, 500, 590}
+	CELL := func(col int, y int32, c uint8, text string) {
+		hAlign := ALIGN_RIGHT
+		if col == 0 {
+			hAlign = ALIGN_LEFT
+		}
+		area.Add(NewLabelAligh(font, columns[col], y, 90, 20, hAlign, ALIGN_MIDDLE, 255, 255, c, text))
+	}
+	ROW := func(y int32, c uint8, title string, s *GameStats) {
+		CELL(0, y, c, title)
+		CELL(1, y, c, ToString(s.Played))
+		CELL(2, y, c, ToString(s.Won))
+		CELL(3, y, c, ToString(s.GetWinRate())+"%")
+		avg, best := "-", "-"
+		if s.Won > 0 {
+			avg = SecToStr(uint64(s.GetAverageTime()))
+			best = SecToStr(uint64(s.BestTime))
+		}
+		CELL(4, y, c, avg)
+		CELL(5, y, c, best)
+	}
+
+	y := int32(170)
+	for i, key := range []string{"", "statPlayed", "statWon", "statWinRate", "statAverage", "statBest"} {
+		if key != "" {
+			CELL(i, y, 0, msg(key))
+		}
+	}
+	for _, d := range append(append([]string{}, DIFFICULTIES...), DIFFICULTY_CUSTOM) {
+		y += 25
+		ROW(y, 255, msg(d), h.GetStats(d))
+	}
+	y += 30
+	ROW(y, 0, msg("statTotal"), h.GetStats(""))
+
+	current, longest := h.GetStreaks()
+	y += 40
+	area.Add(NewLabel(font, 120, y, 255, 255, 255, fmt.Sprintf("%s: %d", msg("currentStreak"), current)))
+	area.Add(NewLabel(font, 400, y, 255, 255, 255, fmt.Sprintf("%s: %d", msg("longestStreak"), longest)))
+
+	area.Add(NewButtonText(120, 450, 130, 25, btnFont, 255, 255, 0, "blue.bmp", msg("exportCSV"),
+		NewExportHistoryCommand(h, "csv", font, area)))
+	area.Add(NewButtonText(260, 450, 130, 25, btnFont, 255, 255, 0, "blue.bmp", msg("exportJSON"),
+		NewExportHistoryCommand(h, "json", font, area)))
+	exitCmd := NewExitCommand(area)
+	area.Add(NewButtonText(590, 450, 90, 25, btnFont, 255, 255, 0, "blue.bmp", msg("ok"), exitCmd))
+	area.Add(NewKeyAccel(sdl.K_ESCAPE, exitCmd))
+	area.Add(NewKeyAccel(sdl.K_RETURN, exitCmd))
+	area.Run()
+}
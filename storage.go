@@ -1,10 +1,15 @@
 package goeinstein
 
 type Storage interface {
+	HasKey(name string) bool
 	GetInt(name string, dflt int) int
 	GetString(name string, dflt string) string
 	SetInt(name string, value int)
 	SetString(name string, value string)
+	GetBool(name string, dflt bool) bool
+	SetBool(name string, value bool)
+	GetFloat(name string, dflt float32) float32
+	SetFloat(name string, value float32)
 	Flush()
 	Close()
 }
@@ -27,6 +32,27 @@ func (s *StorageHolder) Close() {
 	}
 }
 
-var storageHolder = NewStorageHolder()
+// storageHolder is created on the first use, after the command line has set
+// the storage location.
+var storageHolder *StorageHolder
 
-func GetStorage() Storage { return storageHolder.GetStorage() }
+func GetStorage() Storage {
+	if storageHolder == nil {
+		storageHolder = NewStorageHolder()
+	}
+	return storageHolder.GetStorage()
+}
+
+// CloseStorage closes the storage if it was used.
+func CloseStorage() {
+	if storageHolder != nil {
+		storageHolder.Close()
+	}
+}
+
+// ResetStorage closes the storage, the next use reads the storage of the
+// current profile.
+func ResetStorage() {
+	CloseStorage()
+	storageHolder = nil
+}
@@ -1,8 +1,12 @@
 package goeinstein
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 type Type int
@@ -12,6 +16,7 @@ const (
 	DoubleType
 	StringType
 	TableType
+	BoolType
 )
 
 type Value interface {
@@ -69,6 +74,21 @@ func (v StringValue) AsString() string      { return v.value }
 func (v StringValue) AsTable() *Table       { panic("Can't convert string to table") }
 func (v StringValue) Clone() Value          { return NewStringValue(v.value) }
 
+type BoolValue struct {
+	value bool
+}
+
+var _ Value = BoolValue{}
+
+func NewBoolValue(val bool) BoolValue { return BoolValue{val} }
+func (BoolValue) Close()              {}
+func (v BoolValue) GetType() Type     { return BoolType }
+func (v BoolValue) AsInt() int        { return boolToInt[v.value] }
+func (v BoolValue) AsDouble() float32 { return float32(boolToInt[v.value]) }
+func (v BoolValue) AsString() string  { return ToString(v.value) }
+func (v BoolValue) AsTable() *Table   { panic("Can't convert bool to table") }
+func (v BoolValue) Clone() Value      { return NewBoolValue(v.value) }
+
 type TableValue struct {
 	value *Table
 }
@@ -84,6 +104,9 @@ func (v TableValue) AsString() string     { panic("Can't convert table to string
 func (v TableValue) AsTable() *Table      { return v.value }
 func (v TableValue) Clone() Value         { return NewTableValue(NewTableTable(v.value)) }
 
+// Table is a config of the original game: a list of "name = value;" pairs,
+// where a value is a number, a string or a table in braces. Values without a
+// name get array indexes as names.
 type Table struct {
 	fields         map[string]Value
 	lastArrayIndex int
@@ -95,30 +118,41 @@ func NewTableTable(table *Table) *Table {
 	return t
 }
 
-func NewTableFile(fileName string) *Table {
-	t := &Table{
-		fields: map[string]Value{},
-	}
-	t.lastArrayIndex = 0
+func NewTableFile(fileName string) (*Table, error) {
 	bs, err := os.ReadFile(fileName)
 	if err != nil {
-		panic(fmt.Errorf("Error opening file %q: %w", fileName, err)) //nolint:stylecheck
+		return nil, fmt.Errorf("Error opening file %q: %w", fileName, err) //nolint:stylecheck
 	}
-	reader := NewUtfStreamReader(bs)
-	lexal := NewLexal(reader)
-	t.Parse(lexal, false, 0, 0)
-	return t
+	t, err := NewTableText(string(bs))
+	if err != nil {
+		return nil, fmt.Errorf("parse file %q: %w", fileName, err)
+	}
+	return t, nil
 }
 
-func NewTableLexal(lexal *Lexal, line, pos int) *Table {
-	t := &Table{}
-	t.lastArrayIndex = 0
-	t.Parse(lexal, true, line, pos)
-	return t
+func NewTableText(text string) (*Table, error) {
+	t := NewTable()
+	err := t.Parse(NewLexal(text), false, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// NewTableLexal parses a table after the opening brace at (line, pos).
+func NewTableLexal(lexal *Lexal, line, pos int) (*Table, error) {
+	t := NewTable()
+	err := t.Parse(lexal, true, line, pos)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
 }
 
 func NewTable() *Table {
-	t := &Table{}
+	t := &Table{
+		fields: map[string]Value{},
+	}
 	t.lastArrayIndex = 0
 	return t
 }
@@ -137,42 +171,248 @@ func (t *Table) Assign(table *Table) {
 	}
 }
 
-func LexToValue(lexal *Lexal, lexeme *Lexeme) Value {
-	panic("not implemented")
+func LexToValue(lexal *Lexal, lexeme *Lexeme) (Value, error) {
+	switch lexeme.GetType() {
+	case IdentLexeme, StringLexeme:
+		return NewStringValue(lexeme.GetContent()), nil
+	case IntegerLexeme:
+		v, err := strconv.Atoi(lexeme.GetContent())
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer at %s: %w", lexal.PosToStr(lexeme.GetLine(), lexeme.GetPos()), err)
+		}
+		return NewIntValue(v), nil
+	case FloatLexeme:
+		v, err := strconv.ParseFloat(lexeme.GetContent(), 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number at %s: %w", lexal.PosToStr(lexeme.GetLine(), lexeme.GetPos()), err)
+		}
+		return NewDoubleValue(float32(v)), nil
+	case SymbolLexeme:
+		if lexeme.IsSymbol("{") {
+			table, err := NewTableLexal(lexal, lexeme.GetLine(), lexeme.GetPos())
+			if err != nil {
+				return nil, err
+			}
+			return NewTableValue(table), nil
+		}
+	}
+	return nil, fmt.Errorf("invalid value at %s", lexal.PosToStr(lexeme.GetLine(), lexeme.GetPos()))
+}
+
+func (t *Table) HasKey(name string) bool {
+	_, ok := t.fields[name]
+	return ok
+}
+
+func (t *Table) Get(name string) (Value, bool) {
+	v, ok := t.fields[name]
+	return v, ok
+}
+
+// GetKeys returns the names of the table in order: array indexes first.
+func (t *Table) GetKeys() []string {
+	keys := make([]string, 0, len(t.fields))
+	for k := range t.fields {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ni, erri := strconv.Atoi(keys[i])
+		nj, errj := strconv.Atoi(keys[j])
+		switch {
+		case erri == nil && errj == nil:
+			return ni < nj
+		case erri == nil || errj == nil:
+			return erri == nil
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+func (t *Table) GetType(name string) (Type, bool) {
+	v, ok := t.fields[name]
+	if !ok {
+		return 0, false
+	}
+	return v.GetType(), true
 }
 
 func (t *Table) GetInt(name string, dflt int) int {
-	panic("not implemented")
+	v, ok := t.fields[name]
+	if !ok {
+		return dflt
+	}
+	switch v.GetType() {
+	case IntegerType, DoubleType:
+		return v.AsInt()
+	case StringType:
+		if i, err := strconv.Atoi(v.AsString()); err == nil {
+			return i
+		}
+	}
+	return dflt
+}
+
+func (t *Table) GetDouble(name string, dflt float32) float32 {
+	v, ok := t.fields[name]
+	if !ok {
+		return dflt
+	}
+	switch v.GetType() {
+	case IntegerType, DoubleType:
+		return v.AsDouble()
+	case StringType:
+		if f, err := strconv.ParseFloat(v.AsString(), 32); err == nil {
+			return float32(f)
+		}
+	}
+	return dflt
 }
 
 func (t *Table) GetString(name string, dflt string) string {
-	panic("not implemented")
+	v, ok := t.fields[name]
+	if !ok || v.GetType() == TableType {
+		return dflt
+	}
+	return v.AsString()
 }
 
-func (t *Table) SetInt(name string, dflt int) int {
-	panic("not implemented")
+func (t *Table) GetTable(name string) *Table {
+	v, ok := t.fields[name]
+	if !ok || v.GetType() != TableType {
+		return nil
+	}
+	return v.AsTable()
 }
 
-func (t *Table) SetString(name string, dflt string) string {
-	panic("not implemented")
+func (t *Table) SetInt(name string, value int) {
+	t.fields[name] = NewIntValue(value)
 }
 
-func (t *Table) Save(fileName string) {
-	panic("not implemented")
+func (t *Table) SetDouble(name string, value float32) {
+	t.fields[name] = NewDoubleValue(value)
 }
 
-func (t *Table) Parse(i interface{}, b bool, line, pos int) {
-	panic("not implemented")
+func (t *Table) SetString(name string, value string) {
+	t.fields[name] = NewStringValue(value)
 }
 
-func NewUtfStreamReader(bs []byte) interface{} {
-	panic("not implemented")
+func (t *Table) SetTable(name string, value *Table) {
+	t.fields[name] = NewTableValue(value)
 }
 
-func NewLexal(i interface{}) interface{} {
-	panic("not implemented")
+func (t *Table) Save(fileName string) error {
+	var buf bytes.Buffer
+	t.write(&buf, 0)
+	return WriteFileAtomic(fileName, buf.Bytes(), 0o664)
 }
 
-type Lexal interface{}
+func (t *Table) String() string {
+	var buf bytes.Buffer
+	t.write(&buf, 0)
+	return buf.String()
+}
+
+func (t *Table) write(buf *bytes.Buffer, indent int) {
+	spaces := strings.Repeat("    ", indent)
+	for _, k := range t.GetKeys() {
+		buf.WriteString(spaces)
+		if isTableName(k) {
+			buf.WriteString(k)
+		} else {
+			buf.WriteString(EscapeString(k))
+		}
+		buf.WriteString(" = ")
+
+		v := t.fields[k]
+		switch v.GetType() {
+		case IntegerType, BoolType:
+			buf.WriteString(strconv.Itoa(v.AsInt()))
+		case DoubleType:
+			s := strconv.FormatFloat(float64(v.AsDouble()), 'g', -1, 32)
+			if !strings.ContainsAny(s, ".eIN") {
+				s += ".0"
+			}
+			buf.WriteString(s)
+		case StringType:
+			buf.WriteString(EscapeString(v.AsString()))
+		case TableType:
+			buf.WriteString("{\n")
+			v.AsTable().write(buf, indent+1)
+			buf.WriteString(spaces)
+			buf.WriteString("}")
+		}
+		buf.WriteString(";\n")
+	}
+}
 
-type Lexeme interface{}
+// isTableName reports whether the name can be written without quotes.
+func isTableName(name string) bool {
+	if _, err := strconv.Atoi(name); err == nil {
+		return true
+	}
+	for i, ch := range name {
+		if !isIdentChar(ch) || i == 0 && !isIdentStart(ch) {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// Parse reads "name = value" pairs and array values separated by ";" or ",".
+// If needBracket is set the table ends with "}", (line, pos) is the position
+// of the opening brace.
+func (t *Table) Parse(lexal *Lexal, needBracket bool, line, pos int) error {
+	var pending *Lexeme
+	for {
+		lex := pending
+		pending = nil
+		if lex == nil {
+			var err error
+			lex, err = lexal.GetNext()
+			if err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case lex.GetType() == EofLexeme:
+			if needBracket {
+				return fmt.Errorf("table started at %s is never finished", lexal.PosToStr(line, pos))
+			}
+			return nil
+		case needBracket && lex.IsSymbol("}"):
+			return nil
+		case lex.IsSymbol(",") || lex.IsSymbol(";"):
+			continue
+		}
+
+		name := strconv.Itoa(t.lastArrayIndex)
+		isArray := true
+		switch lex.GetType() {
+		case IdentLexeme, StringLexeme, IntegerLexeme:
+			next, err := lexal.GetNext()
+			if err != nil {
+				return err
+			}
+			if next.IsSymbol("=") {
+				name, isArray = lex.GetContent(), false
+				lex, err = lexal.GetNext()
+				if err != nil {
+					return err
+				}
+			} else {
+				pending = next
+			}
+		}
+
+		v, err := LexToValue(lexal, lex)
+		if err != nil {
+			return err
+		}
+		t.fields[name] = v
+		if isArray {
+			t.lastArrayIndex++
+		}
+	}
+}
@@ -3,11 +3,18 @@ package goeinstein
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
 )
 
+// TableStorage keeps values in memory and writes them to conf.cfg on Flush.
+// It is safe for concurrent use.
 type TableStorage struct {
 	// table *Table
+	mu  sync.Mutex
 	mem map[string]Value
 }
 
@@ -27,10 +34,21 @@ func (t *TableStorage) Close() {
 }
 
 func (t *TableStorage) GetFileName() string {
-	return "./einstein/conf.cfg"
+	return filepath.Join(GetProfileConfigDir(), "conf.cfg")
+}
+
+func (t *TableStorage) HasKey(name string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, ok := t.mem[name]
+	return ok
 }
 
 func (t *TableStorage) GetInt(name string, dflt int) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	if v, ok := t.mem[name]; ok {
 		return v.AsInt()
 	}
@@ -39,6 +57,9 @@ func (t *TableStorage) GetInt(name string, dflt int) int {
 }
 
 func (t *TableStorage) GetString(name string, dflt string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	if v, ok := t.mem[name]; ok {
 		return v.AsString()
 	}
@@ -47,18 +68,80 @@ func (t *TableStorage) GetString(name string, dflt string) string {
 }
 
 func (t *TableStorage) SetInt(name string, value int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	t.mem[name] = NewIntValue(value)
 	// t.table.SetInt(name, value)
 }
 
 func (t *TableStorage) SetString(name string, value string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	t.mem[name] = NewStringValue(value)
 	// t.table.SetString(name, value)
 }
 
+// GetBool also reads booleans stored as ints by older versions.
+func (t *TableStorage) GetBool(name string, dflt bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	v, ok := t.mem[name]
+	if !ok {
+		return dflt
+	}
+	switch v.GetType() {
+	case BoolType, IntegerType, DoubleType:
+		return v.AsInt() != 0
+	case StringType:
+		if b, err := strconv.ParseBool(v.AsString()); err == nil {
+			return b
+		}
+	}
+	return dflt
+}
+
+func (t *TableStorage) SetBool(name string, value bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.mem[name] = NewBoolValue(value)
+}
+
+func (t *TableStorage) GetFloat(name string, dflt float32) float32 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	v, ok := t.mem[name]
+	if !ok {
+		return dflt
+	}
+	switch v.GetType() {
+	case BoolType, IntegerType, DoubleType:
+		return v.AsDouble()
+	case StringType:
+		if f, err := strconv.ParseFloat(v.AsString(), 32); err == nil {
+			return float32(f)
+		}
+	}
+	return dflt
+}
+
+func (t *TableStorage) SetFloat(name string, value float32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.mem[name] = NewDoubleValue(value)
+}
+
 func (t *TableStorage) Flush() {
 	// t.table.Save(t.GetFileName())
 
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	out := make(map[string]interface{})
 	for k, v := range t.mem {
 		switch v.GetType() {
@@ -66,6 +149,10 @@ func (t *TableStorage) Flush() {
 			out[k] = v.AsInt()
 		case StringType:
 			out[k] = v.AsString()
+		case BoolType:
+			out[k] = v.AsInt() != 0
+		case DoubleType:
+			out[k] = v.AsDouble()
 		default:
 			panic(fmt.Sprintf("unknown type: %v", v.GetType()))
 		}
@@ -76,7 +163,7 @@ func (t *TableStorage) Flush() {
 		panic(fmt.Errorf("marshal storage: %w", err))
 	}
 
-	err = os.WriteFile(t.GetFileName(), bs, 0664) //nolint:gofumpt
+	err = WriteFileAtomic(t.GetFileName(), bs, 0664) //nolint:gofumpt
 	if err != nil {
 		panic(fmt.Errorf("write file storage: %w", err))
 	}
@@ -102,7 +189,13 @@ func (t *TableStorage) parse() {
 		case int:
 			t.mem[k] = NewIntValue(v)
 		case float64:
-			t.mem[k] = NewIntValue(int(v))
+			if v == math.Trunc(v) {
+				t.mem[k] = NewIntValue(int(v))
+			} else {
+				t.mem[k] = NewDoubleValue(float32(v))
+			}
+		case bool:
+			t.mem[k] = NewBoolValue(v)
 		case string:
 			t.mem[k] = NewStringValue(v)
 		default:
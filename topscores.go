@@ -1,101 +1,279 @@
 package goeinstein
 
-import "github.com/veandco/go-sdl2/sdl"
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+
+	"github.com/vkd/goeinstein/leaderboard"
+)
 
 //nolint:golint,stylecheck
-const MAX_SCORES = 10
+const (
+	MAX_SCORES = 10
+	// SCORES_VERSION is the version of the hall of fame file.
+	SCORES_VERSION = 1
+)
 
 type TopScoreEntry struct {
-	name  string
-	score int
+	Name string `json:"name"`
+	// Time is the solving time in seconds.
+	Time       int       `json:"time"`
+	Date       time.Time `json:"date"`
+	Seed       int64     `json:"seed,omitempty"`
+	Difficulty string    `json:"difficulty"`
+	Size       int       `json:"size"`
+	Hinted     bool      `json:"hinted,omitempty"`
+	// Profile is the ID of the profile which played the game.
+	Profile string `json:"profile,omitempty"`
+	// Day is the date of the daily puzzle, "" for other games.
+	Day string `json:"day,omitempty"`
+	// Moves is the encoded MoveLog of the game, the score is verified by
+	// replaying it.
+	Moves     []byte `json:"moves,omitempty"`
+	Signature string `json:"signature,omitempty"`
 }
 
+// better reports whether e is ranked higher: games without hints first, then
+// the faster one.
+func (e *TopScoreEntry) better(o *TopScoreEntry) bool {
+	if e.Hinted != o.Hinted {
+		return !e.Hinted
+	}
+	return e.Time < o.Time
+}
+
+// TopScores is the board of a difficulty and a grid size or of a daily
+// puzzle.
 type TopScores struct {
-	scores  []*TopScoreEntry
+	Difficulty string           `json:"difficulty"`
+	Size       int              `json:"size"`
+	Day        string           `json:"day,omitempty"`
+	Scores     []*TopScoreEntry `json:"scores"`
+	// online is set on boards fetched from the online leaderboard
+	online bool
+}
+
+func (t *TopScores) IsFull() bool                { return len(t.Scores) >= MAX_SCORES }
+func (t *TopScores) GetScores() []*TopScoreEntry { return t.Scores }
+
+func (t *TopScores) GetKey() string { return boardKey(t.Difficulty, t.Size, t.Day) }
+
+func (t *TopScores) GetTitle() string {
+	title := fmt.Sprintf("%s %dx%d", msg(t.Difficulty), t.Size, t.Size)
+	if t.Day != "" {
+		title = msg("dailyPuzzle") + " " + t.Day
+	}
+	if t.online {
+		title += " (" + msg("online") + ")"
+	}
+	return title
+}
+
+// IsQualified reports whether the entry gets on the board.
+func (t *TopScores) IsQualified(e *TopScoreEntry) bool {
+	return !t.IsFull() || e.better(t.Scores[len(t.Scores)-1])
+}
+
+// Add inserts the entry and returns its position or -1 if it is not
+// qualified.
+func (t *TopScores) Add(e *TopScoreEntry) int {
+	if !t.IsQualified(e) {
+		return -1
+	}
+	pos := sort.Search(len(t.Scores), func(i int) bool { return e.better(t.Scores[i]) })
+	t.Scores = append(t.Scores[:pos], append([]*TopScoreEntry{e}, t.Scores[pos:]...)...)
+	if len(t.Scores) > MAX_SCORES {
+		t.Scores = t.Scores[:MAX_SCORES]
+	}
+	return pos
+}
+
+func BoardKey(difficulty string, size int) string {
+	return fmt.Sprintf("%s/%d", difficulty, size)
+}
+
+func boardKey(difficulty string, size int, day string) string {
+	if day != "" {
+		return "daily/" + day
+	}
+	return BoardKey(difficulty, size)
+}
+
+// HallOfFame keeps the boards in scores.json of the data directory.
+type HallOfFame struct {
+	boards  map[string]*TopScores
 	modifed bool
+	// readOnly is set if the file is of a newer version, it is not
+	// overwritten
+	readOnly bool
+	// online is the online leaderboard, nil if it is not set
+	online leaderboard.Backend
+}
+
+type hallOfFameFile struct {
+	Version int          `json:"version"`
+	Boards  []*TopScores `json:"boards"`
 }
 
-func (t *TopScores) IsFull() bool { return len(t.scores) >= MAX_SCORES }
+func GetScoresFileName() string {
+	return filepath.Join(GetDataDir(), "scores.json")
+}
 
-func NewTopScores() *TopScores {
-	t := &TopScores{}
-	storage := GetStorage()
+// NewHallOfFame reads the boards. Without the file the scores of older
+// versions are taken from the storage into the normal board.
+func NewHallOfFame() *HallOfFame {
+	h := &HallOfFame{
+		boards: make(map[string]*TopScores),
+		online: GetLeaderboard(),
+	}
 
+	bs, err := os.ReadFile(GetScoresFileName())
+	if os.IsNotExist(err) {
+		h.migrateStorage()
+		return h
+	}
+	if err == nil {
+		var f hallOfFameFile
+		err = json.Unmarshal(bs, &f)
+		if err == nil && f.Version > SCORES_VERSION {
+			h.readOnly = true
+			err = fmt.Errorf("version %d is newer than supported %d", f.Version, SCORES_VERSION)
+		}
+		for _, b := range f.Boards {
+			board := h.getBoard(b.Difficulty, b.Size, b.Day)
+			for _, e := range b.Scores {
+				board.Add(e)
+			}
+		}
+	}
+	if err != nil {
+		log.Printf("Error on read hall of fame (filename: %q): %v", GetScoresFileName(), err)
+		if !h.readOnly {
+			// the next save would overwrite the scores which are not read
+			backup := GetScoresFileName() + ".bak"
+			if err = os.Rename(GetScoresFileName(), backup); err != nil {
+				log.Printf("Error on keep unreadable hall of fame: %v", err)
+				h.readOnly = true
+			} else {
+				log.Printf("Unreadable hall of fame is kept as %q", backup)
+			}
+		}
+	}
+	h.modifed = false
+	return h
+}
+
+func (h *HallOfFame) migrateStorage() {
+	storage := GetStorage()
 	for i := 0; i < MAX_SCORES; i++ {
 		score := storage.GetInt("top_score_"+ToString(i), -1)
 		if score < 0 {
 			break
 		}
-		name := storage.GetString("top_name_"+ToString(i), "")
-		t.Add(name, score)
+		h.Add(&TopScoreEntry{
+			Name:       storage.GetString("top_name_"+ToString(i), ""),
+			Time:       score,
+			Difficulty: DIFFICULTY_NORMAL,
+			Size:       PUZZLE_SIZE,
+		})
 	}
+}
 
-	t.modifed = false
-	return t
+func (h *HallOfFame) Close() {
+	h.Save()
 }
 
-func (t *TopScores) Close() {
-	t.Save()
+// GetBoard returns the board, an empty one is created if needed.
+func (h *HallOfFame) GetBoard(difficulty string, size int) *TopScores {
+	return h.getBoard(difficulty, size, "")
 }
 
-func (t *TopScores) Add(name string, score int) int {
-	if score >= t.GetMaxScore() || len(t.scores) < 1 {
-		if !t.IsFull() {
-			e := &TopScoreEntry{name, score}
-			t.scores = append(t.scores, e)
-			t.modifed = true
-			return len(t.scores) - 1
-		}
-		return -1
+// GetDailyBoard returns the board of the daily puzzle of the day.
+func (h *HallOfFame) GetDailyBoard(day string) *TopScores {
+	return h.getBoard(DAILY_DIFFICULTY, PUZZLE_SIZE, day)
+}
+
+func (h *HallOfFame) getBoard(difficulty string, size int, day string) *TopScores {
+	key := boardKey(difficulty, size, day)
+	b, ok := h.boards[key]
+	if !ok {
+		b = &TopScores{Difficulty: difficulty, Size: size, Day: day}
+		h.boards[key] = b
 	}
+	return b
+}
 
-	var pos int
-	for i, e := range t.scores {
-		if e.score > score {
-			ne := &TopScoreEntry{name, score}
-			t.scores = append(t.scores[:i], append([]*TopScoreEntry{ne}, t.scores[i:]...)...)
-			t.modifed = true
-			break
+// GetBoards returns the boards in the order of difficulties, the current
+// grid size first. The board of the current difficulty is always included,
+// boards of daily puzzles are not.
+func (h *HallOfFame) GetBoards() []*TopScores {
+	h.GetBoard(GetDifficulty(), PUZZLE_SIZE)
+
+	order := make(map[string]int)
+	for i, d := range DIFFICULTIES {
+		order[d] = i + 1
+	}
+	order[DIFFICULTY_CUSTOM] = len(DIFFICULTIES) + 1
+	boards := make([]*TopScores, 0, len(h.boards))
+	for _, b := range h.boards {
+		if b.Day == "" && (len(b.Scores) > 0 || b.GetKey() == BoardKey(GetDifficulty(), PUZZLE_SIZE)) {
+			boards = append(boards, b)
 		}
-		pos++
 	}
+	sort.Slice(boards, func(i, j int) bool {
+		a, b := boards[i], boards[j]
+		if a.Size != b.Size {
+			return a.Size == PUZZLE_SIZE || b.Size != PUZZLE_SIZE && a.Size < b.Size
+		}
+		if order[a.Difficulty] != order[b.Difficulty] {
+			return order[a.Difficulty] < order[b.Difficulty]
+		}
+		return a.Difficulty < b.Difficulty
+	})
+	return boards
+}
 
-	for len(t.scores) > MAX_SCORES {
-		t.modifed = true
-		t.scores = t.scores[:len(t.scores)-1]
-	}
+func (h *HallOfFame) IsQualified(e *TopScoreEntry) bool {
+	return h.getBoard(e.Difficulty, e.Size, e.Day).IsQualified(e)
+}
 
-	if t.modifed {
-		return pos
+func (h *HallOfFame) Add(e *TopScoreEntry) int {
+	pos := h.getBoard(e.Difficulty, e.Size, e.Day).Add(e)
+	if pos >= 0 {
+		h.modifed = true
 	}
-	return -1
+	return pos
 }
 
-func (t *TopScores) Save() {
-	if !t.modifed {
+func (h *HallOfFame) Save() {
+	if !h.modifed || h.readOnly {
 		return
 	}
 
-	storage := GetStorage()
-	var no int
-
-	for _, e := range t.scores {
-		storage.SetString("top_name_"+ToString(no), e.name)
-		storage.SetInt("top_score_"+ToString(no), e.score)
-		no++
+	f := hallOfFameFile{Version: SCORES_VERSION}
+	for _, b := range h.boards {
+		if len(b.Scores) > 0 {
+			f.Boards = append(f.Boards, b)
+		}
 	}
+	sort.Slice(f.Boards, func(i, j int) bool { return f.Boards[i].GetKey() < f.Boards[j].GetKey() })
 
-	storage.Flush()
-	t.modifed = false
-}
-
-func (t *TopScores) GetScores() []*TopScoreEntry { return t.scores }
-
-func (t *TopScores) GetMaxScore() int {
-	if len(t.scores) < 1 {
-		return -1
+	bs, err := json.MarshalIndent(f, "", "\t")
+	if err == nil {
+		err = WriteFileAtomic(GetScoresFileName(), bs, 0o664)
+	}
+	if err != nil {
+		log.Printf("Error on save hall of fame: %v", err)
+		return
 	}
-	return t.scores[len(t.scores)-1].score
+	h.modifed = false
 }
 
 type ScoresWindow struct {
@@ -104,7 +282,7 @@ type ScoresWindow struct {
 
 func NewScoresWindow(x, y int32, scores *TopScores, highlight int) *ScoresWindow {
 	sw := &ScoresWindow{}
-	sw.Window = *NewWindow(x, y, 320, 350, "blue.bmp")
+	sw.Window = *NewWindow(x, y, 400, 350, "blue.bmp")
 
 	titleFont := NewFont("nova.ttf", 26)
 	entryFont := NewFont("laudcn2.ttf", 14)
@@ -112,11 +290,14 @@ func NewScoresWindow(x, y int32, scores *TopScores, highlight int) *ScoresWindow
 
 	txt := msg("topScores")
 	w := titleFont.GetWidth(txt)
-	titleFont.DrawSurface(sw.background, (320-w)/2, 15, 255, 255, 0, true, txt)
+	titleFont.DrawSurface(sw.background, (400-w)/2, 15, 255, 255, 0, true, txt)
+	txt = scores.GetTitle()
+	w = entryFont.GetWidth(txt)
+	entryFont.DrawSurface(sw.background, (400-w)/2, 50, 255, 255, 255, true, txt)
 
 	list := scores.GetScores()
 	no := 1
-	pos := int32(70)
+	pos := int32(80)
 	for _, e := range list {
 		s := ToString(no) + "."
 		w := entryFont.GetWidth(s)
@@ -127,33 +308,183 @@ func NewScoresWindow(x, y int32, scores *TopScores, highlight int) *ScoresWindow
 			c = 255
 		}
 		entryFont.DrawSurface(sw.background, 30-w, pos, 255, 255, c, true, s)
-		rect := &sdl.Rect{40, pos - 20, 180, 40}
+		rect := &sdl.Rect{40, pos - 20, 170, 40}
 		sw.background.SetClipRect(rect)
-		entryFont.DrawSurface(sw.background, 40, pos, 255, 255, c, true, e.name)
+		entryFont.DrawSurface(sw.background, 40, pos, 255, 255, c, true, e.Name)
 		sw.background.SetClipRect(nil)
-		s = SecToStr(uint64(e.score))
+		if !e.Date.IsZero() {
+			entryFont.DrawSurface(sw.background, 215, pos, 255, 255, c, true, e.Date.Local().Format("2006-01-02"))
+		}
+		if e.Hinted {
+			entryFont.DrawSurface(sw.background, 300, pos, 255, 255, c, true, "*")
+		}
+		if !scores.online {
+			if done, err := e.GetVerified(); done && err != nil {
+				entryFont.DrawSurface(sw.background, 310, pos, 255, 0, 0, true, "?")
+			}
+		}
+		s = SecToStr(uint64(e.Time))
 		w = timeFont.GetWidth(s)
-		timeFont.DrawSurface(sw.background, 305-w, pos, 255, 255, c, true, s)
+		timeFont.DrawSurface(sw.background, 385-w, pos, 255, 255, c, true, s)
 		pos += 20
 		no++
 	}
+	if hasReplays(scores) {
+		txt = msg("replayHint")
+		w = entryFont.GetWidth(txt)
+		entryFont.DrawSurface(sw.background, (400-w)/2, 285, 255, 255, 255, true, txt)
+	}
 	return sw
 }
 
-func ShowScoresWindow(parentArea *Area, scores *TopScores) {
-	ShowScoresWindowHighlight(parentArea, scores, -1)
+// HasReplay reports whether the moves of the entry can be replayed.
+func (e *TopScoreEntry) HasReplay() bool { return len(e.Moves) > 0 && e.Seed != 0 }
+
+func hasReplays(scores *TopScores) bool {
+	for _, e := range scores.GetScores() {
+		if !scores.online && e.HasReplay() {
+			return true
+		}
+	}
+	return false
 }
 
-func ShowScoresWindowHighlight(parentArea *Area, scores *TopScores, highlight int) {
-	area := NewArea()
+// isVerifying reports whether moves of the local board are still replayed.
+func isVerifying(scores *TopScores) bool {
+	if scores.online {
+		return false
+	}
+	for _, e := range scores.GetScores() {
+		if done, _ := e.GetVerified(); !done {
+			return true
+		}
+	}
+	return false
+}
+
+// scoresWaiter finishes the event loop of the scores window when ready
+// returns true, so the window is built again.
+type scoresWaiter struct {
+	area  *Area
+	ready func() bool
+}
+
+var _ TimerHandler = (*scoresWaiter)(nil)
 
+func (w *scoresWaiter) OnTimer() {
+	if w.ready() {
+		w.area.FinishEventLoop()
+	}
+}
+
+func ShowScoresWindow(parentArea *Area, scores *HallOfFame) {
+	ShowScoresWindowHighlight(parentArea, scores, scores.GetBoard(GetDifficulty(), PUZZLE_SIZE), -1)
+}
+
+// ShowScoresWindowHighlight shows the board first. The other boards are
+// switched with the arrow buttons and keys. If there is an online
+// leaderboard, its boards are shown on a switch.
+func ShowScoresWindowHighlight(parentArea *Area, scores *HallOfFame, first *TopScores, highlight int) {
 	font := NewFont("laudcn2.ttf", 16)
-	area.Add(parentArea)
-	area.Add(NewScoresWindow(240, 125, scores, highlight))
-	exitCmd := NewExitCommand(area)
-	area.Add(NewButtonText(348, 430, 90, 25, font, 255, 255, 0, "blue.bmp", msg("ok"), exitCmd))
-	area.Add(NewKeyAccel(sdl.K_ESCAPE, exitCmd))
-	area.Run()
+	boards := scores.GetBoards()
+	cur := -1
+	for i, b := range boards {
+		if b == first {
+			cur = i
+		}
+	}
+	if cur < 0 {
+		boards = append([]*TopScores{first}, boards...)
+		cur = 0
+	}
+
+	var online bool
+	var fetch *OnlineBoard
+	for {
+		area := NewArea()
+		var closed bool
+		rebuild := func(fn func()) Command {
+			return FnCommand(func() {
+				fn()
+				area.FinishEventLoop()
+			})
+		}
+
+		board := boards[cur]
+		hl := -1
+		if board == first && !online {
+			hl = highlight
+		}
+		// the local board is shown until the online one is fetched
+		if online && (fetch == nil || fetch.GetKey() != board.GetKey()) {
+			fetch = scores.FetchOnlineBoard(board)
+		}
+		var waiting bool
+		if online {
+			b, done, err := fetch.Get()
+			switch {
+			case !done:
+				waiting = true
+			case err != nil:
+				log.Printf("Error on fetch online scores: %v", err)
+				ShowMessageWindow(parentArea, "redpattern.bmp", 400, 80, font, 255, 255, 255, msg("onlineError"))
+				online = false
+				fetch = nil
+			default:
+				board = b
+				board.online = true
+			}
+		} else {
+			fetch = nil
+		}
+		// the board is built again when the online board is fetched or the
+		// replays of its entries are done
+		verifying := isVerifying(board)
+		if waiting || verifying {
+			fetch := fetch
+			area.SetTimer(100, &scoresWaiter{area: area, ready: func() bool {
+				if waiting {
+					_, done, _ := fetch.Get()
+					return done
+				}
+				return !isVerifying(board)
+			}})
+		}
+		area.Add(parentArea)
+		area.Add(NewScoresWindow(200, 125, board, hl))
+		if !board.online {
+			for i, e := range board.GetScores() {
+				if e.HasReplay() {
+					e := e
+					area.Add(NewClickArea(210, 125+80+int32(i)*20, 380, 20, rebuild(func() { ShowEntryReplay(area, e) })))
+				}
+			}
+		}
+		exitCmd := rebuild(func() { closed = true })
+		area.Add(NewButtonText(355, 430, 90, 25, font, 255, 255, 0, "blue.bmp", msg("ok"), exitCmd))
+		area.Add(NewKeyAccel(sdl.K_ESCAPE, exitCmd))
+		area.Add(NewKeyAccel(sdl.K_RETURN, exitCmd))
+		if scores.HasOnline() {
+			text := msg("online")
+			if online {
+				text = msg("local")
+			}
+			area.Add(NewButtonText(450, 430, 90, 25, font, 255, 255, 0, "blue.bmp", text, rebuild(func() { online = !online })))
+		}
+		if len(boards) > 1 {
+			prevCmd := rebuild(func() { cur = (cur + len(boards) - 1) % len(boards) })
+			nextCmd := rebuild(func() { cur = (cur + 1) % len(boards) })
+			area.Add(NewButtonText(215, 430, 40, 25, font, 255, 255, 0, "blue.bmp", "<", prevCmd))
+			area.Add(NewButtonText(545, 430, 40, 25, font, 255, 255, 0, "blue.bmp", ">", nextCmd))
+			area.Add(NewKeyAccel(sdl.K_LEFT, prevCmd))
+			area.Add(NewKeyAccel(sdl.K_RIGHT, nextCmd))
+		}
+		area.Run()
+
+		if closed || IsQuitRequested() {
+			return
+		}
+	}
 }
 
 func EnterNameDialog(parentArea *Area) string {
@@ -163,7 +494,7 @@ func EnterNameDialog(parentArea *Area) string {
 	area.Add(parentArea)
 	area.Add(NewWindow(170, 280, 460, 100, "blue.bmp"))
 	storage := GetStorage()
-	name := storage.GetString("lastName", msg("anonymous"))
+	name := storage.GetString("lastName", GetProfiles().GetCurrent().GetName())
 	area.Add(NewLabel(font, 180, 300, 255, 255, 0, msg("enterName")))
 	area.Add(NewInputField(350, 300, 270, 26, "blue.bmp", &name, 20, 255, 255, 0, font))
 	exitCmd := NewExitCommand(area)
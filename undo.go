@@ -0,0 +1,199 @@
+package goeinstein
+
+import (
+	"fmt"
+	"io"
+)
+
+//nolint:golint,nosnakecase,stylecheck
+const (
+	// MAX_UNDO_STEPS limits both stacks of the undo history, older steps are
+	// dropped. Every step keeps the whole state and is written to saves.
+	MAX_UNDO_STEPS = 500
+)
+
+// Snapshot is the state of the puzzle and both hints lists which is restored
+// by undo and redo.
+type Snapshot struct {
+	Possib      Possibilities
+	VerExcluded []bool
+	HorExcluded []bool
+}
+
+// UndoStep is a state to return to. Move is the move made from the state of
+// an undo step, or undone to it for a redo step. It is nil if only the hints
+// lists were changed.
+type UndoStep struct {
+	State Snapshot
+	Move  *Move
+}
+
+// UndoHistory keeps the undo and the redo stacks of a game.
+type UndoHistory struct {
+	undo []*UndoStep
+	redo []*UndoStep
+}
+
+func NewUndoHistory() *UndoHistory {
+	return &UndoHistory{}
+}
+
+func (h *UndoHistory) CanUndo() bool { return len(h.undo) > 0 }
+func (h *UndoHistory) CanRedo() bool { return len(h.redo) > 0 }
+
+// Push adds the state before a change, the redo stack is dropped. The oldest
+// step is dropped if there are MAX_UNDO_STEPS of them.
+func (h *UndoHistory) Push(s *UndoStep) {
+	if len(h.undo) >= MAX_UNDO_STEPS {
+		n := copy(h.undo, h.undo[len(h.undo)-MAX_UNDO_STEPS+1:])
+		h.undo = h.undo[:n]
+	}
+	h.undo = append(h.undo, s)
+	h.redo = nil
+}
+
+// Undo returns the step to go back to and remembers current for redo.
+func (h *UndoHistory) Undo(current Snapshot) *UndoStep {
+	if len(h.undo) == 0 {
+		return nil
+	}
+	s := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+	h.redo = append(h.redo, &UndoStep{State: current, Move: s.Move})
+	return s
+}
+
+// Redo returns the step to go forward to and remembers current for undo.
+func (h *UndoHistory) Redo(current Snapshot) *UndoStep {
+	if len(h.redo) == 0 {
+		return nil
+	}
+	s := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+	h.undo = append(h.undo, &UndoStep{State: current, Move: s.Move})
+	return s
+}
+
+func (h *UndoHistory) Save(stream io.Writer) {
+	for _, steps := range [][]*UndoStep{h.undo, h.redo} {
+		WriteInt(stream, len(steps))
+		for _, s := range steps {
+			s.State.Possib.Save(stream)
+			saveExcluded(stream, s.State.VerExcluded)
+			saveExcluded(stream, s.State.HorExcluded)
+			if s.Move != nil {
+				WriteInt(stream, 1)
+				s.Move.Save(stream)
+			} else {
+				WriteInt(stream, 0)
+			}
+		}
+	}
+}
+
+// NewUndoHistoryStream reads the history saved by Save. verHints and horHints
+// are the sizes of the hints lists of the game.
+func NewUndoHistoryStream(stream io.Reader, verHints, horHints int) (*UndoHistory, error) {
+	h := &UndoHistory{}
+	for _, steps := range []*[]*UndoStep{&h.undo, &h.redo} {
+		cnt, err := ReadInt(stream)
+		if err != nil {
+			return nil, fmt.Errorf("read undo count: %w", err)
+		}
+		if cnt < 0 || cnt > MAX_UNDO_STEPS {
+			return nil, fmt.Errorf("wrong undo count: %d", cnt)
+		}
+		for i := 0; i < cnt; i++ {
+			s, err := newUndoStepStream(stream, verHints, horHints)
+			if err != nil {
+				return nil, err
+			}
+			*steps = append(*steps, s)
+		}
+	}
+	return h, nil
+}
+
+func newUndoStepStream(stream io.Reader, verHints, horHints int) (*UndoStep, error) {
+	possib, err := NewPossibilitiesStream(stream)
+	if err != nil {
+		return nil, fmt.Errorf("read undo possibilities: %w", err)
+	}
+	s := &UndoStep{State: Snapshot{Possib: *possib}}
+	if s.State.VerExcluded, err = readExcluded(stream, verHints); err != nil {
+		return nil, err
+	}
+	if s.State.HorExcluded, err = readExcluded(stream, horHints); err != nil {
+		return nil, err
+	}
+	hasMove, err := ReadInt(stream)
+	if err != nil {
+		return nil, fmt.Errorf("read undo move: %w", err)
+	}
+	if hasMove != 0 {
+		m, err := NewMoveStream(stream)
+		if err != nil {
+			return nil, err
+		}
+		s.Move = m
+	}
+	return s, nil
+}
+
+func saveExcluded(stream io.Writer, excluded []bool) {
+	WriteInt(stream, len(excluded))
+	for _, e := range excluded {
+		WriteInt(stream, boolToInt[e])
+	}
+}
+
+func readExcluded(stream io.Reader, qty int) ([]bool, error) {
+	cnt, err := ReadInt(stream)
+	if err != nil {
+		return nil, fmt.Errorf("read excluded hints: %w", err)
+	}
+	if cnt != qty {
+		return nil, fmt.Errorf("wrong excluded hints count: %d", cnt)
+	}
+	excluded := make([]bool, cnt)
+	for i := range excluded {
+		v, err := ReadInt(stream)
+		if err != nil {
+			return nil, fmt.Errorf("read excluded hints: %w", err)
+		}
+		excluded[i] = v != 0
+	}
+	return excluded, nil
+}
+
+type UndoCommand struct {
+	game *Game
+}
+
+var _ Command = (*UndoCommand)(nil)
+
+func NewUndoCommand(g *Game) *UndoCommand {
+	return &UndoCommand{game: g}
+}
+
+func (u *UndoCommand) DoAction() {
+	if u.game.Undo() {
+		sound.Play("click.wav")
+	}
+}
+
+type RedoCommand struct {
+	game *Game
+}
+
+var _ Command = (*RedoCommand)(nil)
+
+func NewRedoCommand(g *Game) *RedoCommand {
+	return &RedoCommand{game: g}
+}
+
+func (r *RedoCommand) DoAction() {
+	if r.game.Redo() {
+		sound.Play("click.wav")
+	}
+}
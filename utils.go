@@ -298,6 +298,29 @@ func ShowMessageWindow(parentArea *Area, pattern string, width, height int32, fo
 	sound.Play("click.wav")
 }
 
+// AskYesNo shows the question and returns true if the player agreed.
+func AskYesNo(parentArea *Area, font *Font, question string) bool {
+	area := NewArea()
+
+	var width, height int32 = 400, 110
+	x := (screen.GetWidth() - width) / 2
+	y := (screen.GetHeight() - height) / 2
+
+	var ok bool
+	okCmd := NewOkCommand(area, &ok)
+	exitCmd := NewExitCommand(area)
+	area.Add(parentArea)
+	area.Add(NewWindowFrame(x, y, width, height, "redpattern.bmp", 6))
+	area.Add(NewLabelAligh(font, x, y+10, width, 50, ALIGN_CENTER, ALIGN_MIDDLE, 255, 255, 255, question))
+	area.Add(NewButtonText(x+width/2-90, y+height-40, 80, 25, font, 255, 255, 0, "redpattern.bmp", msg("yes"), okCmd))
+	area.Add(NewButtonText(x+width/2+10, y+height-40, 80, 25, font, 255, 255, 0, "redpattern.bmp", msg("no"), exitCmd))
+	area.Add(NewKeyAccel(sdl.K_RETURN, okCmd))
+	area.Add(NewKeyAccel(sdl.K_ESCAPE, exitCmd))
+	area.Run()
+	sound.Play("click.wav")
+	return ok
+}
+
 func DrawBevel(s *sdl.Surface, left, top, width, height int32, raised bool, size int32) {
 	var k, f, kAdv, fAdv float64
 	if raised {
@@ -343,34 +366,66 @@ func EnsureDirExists(fileName string) {
 	}
 }
 
-func ReadInt(r io.Reader) int {
+func ReadInt(r io.Reader) (int, error) {
 	buf := make([]byte, 4)
-	n, err := r.Read(buf)
+	_, err := io.ReadFull(r, buf)
 	if err != nil {
-		panic(fmt.Errorf("readInt: %w", err))
-	}
-	if n != 4 {
-		panic(fmt.Errorf("wrong len of read bytes: %d", n))
+		return 0, fmt.Errorf("read int: %w", err)
 	}
 
-	return int(buf[0]) + int(buf[1])*256 + int(buf[2])*256*256 + int(buf[3])*256*256*256
+	return int(buf[0]) + int(buf[1])*256 + int(buf[2])*256*256 + int(buf[3])*256*256*256, nil
 }
 
-func ReadString(stream io.Reader) string {
-	no := ReadInt(stream)
-	if no <= 0 {
-		panic(fmt.Errorf("wrong read string len (n=%d)", no))
+// ReadInt64 reads an int64 written by WriteInt64.
+func ReadInt64(r io.Reader) (int64, error) {
+	lo, err := ReadInt(r)
+	if err != nil {
+		return 0, err
+	}
+	hi, err := ReadInt(r)
+	if err != nil {
+		return 0, err
+	}
+	return int64(uint64(hi)<<32 | uint64(lo)), nil
+}
+
+//nolint:golint,nosnakecase,stylecheck
+const MAX_STRING_LEN = 4096
+
+func ReadString(stream io.Reader) (string, error) {
+	no, err := ReadInt(stream)
+	if err != nil {
+		return "", err
+	}
+	if no < 0 || no > MAX_STRING_LEN {
+		return "", fmt.Errorf("wrong read string len (n=%d)", no)
 	}
 	bs := make([]byte, no)
 
-	n, err := stream.Read(bs)
+	_, err = io.ReadFull(stream, bs)
 	if err != nil {
-		panic(fmt.Errorf("read stream: %w", err))
+		return "", fmt.Errorf("read string: %w", err)
 	}
-	if n != no {
-		panic(fmt.Errorf("read less than expected: %d != %d", n, no))
+	return string(bs), nil
+}
+
+// ReadLegacyString reads a NUL-terminated string of the original game.
+func ReadLegacyString(stream io.Reader) (string, error) {
+	var bs []byte
+	b := make([]byte, 1)
+	for {
+		_, err := io.ReadFull(stream, b)
+		if err != nil {
+			return "", fmt.Errorf("read string: %w", err)
+		}
+		if b[0] == 0 {
+			return string(bs), nil
+		}
+		if len(bs) >= MAX_STRING_LEN {
+			return "", fmt.Errorf("wrong read string len (n>%d)", MAX_STRING_LEN)
+		}
+		bs = append(bs, b[0])
 	}
-	return string(bs)
 }
 
 func WriteInt(w io.Writer, v int) {
@@ -392,6 +447,12 @@ func WriteInt(w io.Writer, v int) {
 	}
 }
 
+// WriteInt64 writes v as two ints, the low half first.
+func WriteInt64(w io.Writer, v int64) {
+	WriteInt(w, int(uint64(v)&0xFFFFFFFF))
+	WriteInt(w, int(uint64(v)>>32))
+}
+
 func WriteString(stream io.Writer, value string) {
 	WriteInt(stream, len(value))
 	n, err := stream.Write([]byte(value))
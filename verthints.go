@@ -1,6 +1,7 @@
 package goeinstein
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/veandco/go-sdl2/sdl"
@@ -25,6 +26,7 @@ type VertHints struct {
 	numbersArr    []int
 	showExcluded  bool
 	highlighted   int
+	recorder      MoveRecorder
 }
 
 func NewVertHints(is *IconSet, r *Rules) *VertHints {
@@ -34,17 +36,29 @@ func NewVertHints(is *IconSet, r *Rules) *VertHints {
 	return h
 }
 
-func NewVertHintsStream(is *IconSet, rl *Rules, stream io.Reader) *VertHints {
+func NewVertHintsStream(is *IconSet, rl *Rules, stream io.Reader) (*VertHints, error) {
 	v := &VertHints{}
 	v.iconSet = is
 
-	qty := ReadInt(stream)
+	qty, err := ReadInt(stream)
+	if err != nil {
+		return nil, err
+	}
 
 	for i := 0; i < qty; i++ {
-		no := ReadInt(stream)
+		no, err := ReadInt(stream)
+		if err != nil {
+			return nil, err
+		}
+		if no < 0 || no >= len(*rl) {
+			return nil, fmt.Errorf("wrong rule number: %d", no)
+		}
 		v.numbersArr = append(v.numbersArr, no)
 		r := GetRule(rl, no)
-		excluded := ReadInt(stream)
+		excluded, err := ReadInt(stream)
+		if err != nil {
+			return nil, err
+		}
 		if excluded > 0 {
 			v.excludedRules = append(v.excludedRules, r)
 			v.rules = append(v.rules, nil)
@@ -54,11 +68,15 @@ func NewVertHintsStream(is *IconSet, rl *Rules, stream io.Reader) *VertHints {
 		}
 	}
 
-	v.showExcluded = ReadInt(stream) > 0
+	showExcluded, err := ReadInt(stream)
+	if err != nil {
+		return nil, err
+	}
+	v.showExcluded = showExcluded > 0
 
 	x, y, _ := sdl.GetMouseState()
 	v.highlighted = v.GetRuleNo(x, y)
-	return v
+	return v, nil
 }
 
 func (v *VertHints) Reset(r *Rules) {
@@ -105,7 +123,7 @@ func (v *VertHints) DrawCellUpdate(col int, addToUpdate bool) {
 		}
 	}
 
-	if options.AutoHints.value {
+	if options.AutoHints.Value() {
 		if v.showExcluded {
 			if r == nil && col < len(v.rules) {
 				r = v.rules[col]
@@ -116,7 +134,7 @@ func (v *VertHints) DrawCellUpdate(col int, addToUpdate bool) {
 	}
 
 	if r != nil {
-		r.Draw(x, y, v.iconSet, v.highlighted == col)
+		DrawRule(r, x, y, v.iconSet, v.highlighted == col)
 	} else {
 		screen.Draw(x, y, v.iconSet.GetEmptyHintIcon())
 		screen.Draw(x, y+VERTHINTS_TILE_HEIGHT, v.iconSet.GetEmptyHintIcon())
@@ -141,12 +159,14 @@ func (v *VertHints) OnMouseButtonDown(button uint8, x, y int32) bool {
 		if v.showExcluded {
 			r := v.excludedRules[no]
 			if r != nil {
+				v.recordHint(MOVE_HINT_RESTORE, no)
 				sound.Play("whizz.wav")
 				v.rules[no] = r
 				v.excludedRules[no] = nil
 				v.DrawCell(no)
 			}
-		} else {
+		} else if v.rules[no] != nil {
+			v.recordHint(MOVE_HINT_EXCLUDE, no)
 			v.Exclude(no)
 		}
 	}
@@ -164,6 +184,40 @@ func (v *VertHints) Exclude(no int) {
 	}
 }
 
+// SetRecorder sets the recorder of the hints excluded and restored by the
+// player, it may be nil.
+func (v *VertHints) SetRecorder(r MoveRecorder) { v.recorder = r }
+
+func (v *VertHints) recordHint(kind, no int) {
+	if v.recorder != nil {
+		v.recorder.RecordMove(Move{Kind: kind, Col: no, Row: int(SHOW_VERT)})
+	}
+}
+
+// GetExcluded returns which hints are excluded.
+func (v *VertHints) GetExcluded() []bool {
+	excluded := make([]bool, len(v.numbersArr))
+	for i := range excluded {
+		excluded[i] = v.rules[i] == nil
+	}
+	return excluded
+}
+
+// SetExcluded excludes and restores hints as returned by GetExcluded.
+func (v *VertHints) SetExcluded(excluded []bool) {
+	for i, e := range excluded {
+		if i >= len(v.rules) {
+			break
+		}
+		if e && v.rules[i] != nil {
+			v.excludedRules[i], v.rules[i] = v.rules[i], nil
+		} else if !e && v.excludedRules[i] != nil {
+			v.rules[i], v.excludedRules[i] = v.excludedRules[i], nil
+		}
+	}
+	v.Draw()
+}
+
 func (v *VertHints) ToggleExcluded() {
 	v.showExcluded = !v.showExcluded
 	v.Draw()
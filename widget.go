@@ -2,7 +2,6 @@ package goeinstein
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/veandco/go-sdl2/sdl"
 )
@@ -213,6 +212,7 @@ type KeyAccel struct {
 	Widget
 
 	key     SDLKey
+	mod     sdl.Keymod
 	command Command
 }
 
@@ -223,7 +223,20 @@ func NewKeyAccel(sym sdl.Keycode, cmd Command) *KeyAccel {
 	}
 }
 
+// NewKeyAccelMod returns an accelerator of the key pressed with one of the
+// modifiers, e.g. sdl.KMOD_CTRL.
+func NewKeyAccelMod(sym sdl.Keycode, mod sdl.Keymod, cmd Command) *KeyAccel {
+	return &KeyAccel{
+		key:     sym,
+		mod:     mod,
+		command: cmd,
+	}
+}
+
 func (ka *KeyAccel) OnKeyDown(k SDLKey, ch sdl.Scancode) bool {
+	if ka.mod != 0 && sdl.GetModState()&ka.mod == 0 {
+		return false
+	}
 	if ka.key == k {
 		if ka.command != nil {
 			ka.command.DoAction()
@@ -233,6 +246,27 @@ func (ka *KeyAccel) OnKeyDown(k SDLKey, ch sdl.Scancode) bool {
 	return false
 }
 
+// ClickArea runs the command when the rectangle is clicked.
+type ClickArea struct {
+	Widget
+
+	x, y, w, h int32
+	command    Command
+}
+
+func NewClickArea(x, y, w, h int32, cmd Command) *ClickArea {
+	return &ClickArea{x: x, y: y, w: w, h: h, command: cmd}
+}
+
+func (c *ClickArea) OnMouseButtonDown(button uint8, x, y int32) bool {
+	if button != 1 || !IsInRect(x, y, c.x, c.y, c.w, c.h) {
+		return false
+	}
+	sound.Play("click.wav")
+	c.command.DoAction()
+	return true
+}
+
 type TimerHandler interface {
 	OnTimer()
 }
@@ -341,11 +375,21 @@ func (a *Area) HandleEvent(event sdl.Event) {
 			}
 		}
 	case *sdl.QuitEvent:
-		os.Exit(0)
+		RequestQuit()
 	}
 }
 
+// quitRequested is set when the window is closed. Every Area.Run returns
+// at once, so the nested event loops unwind up to Main which cleans up.
+var quitRequested bool
+
+func RequestQuit()          { quitRequested = true }
+func IsQuitRequested() bool { return quitRequested }
+
 func (a *Area) Run() {
+	if quitRequested {
+		return
+	}
 	a.terminate = false
 	var event sdl.Event
 
@@ -355,7 +399,7 @@ func (a *Area) Run() {
 
 	runTimer := a.timer != nil
 	var dispetchEvent bool
-	for !a.terminate {
+	for !a.terminate && !quitRequested {
 		dispetchEvent = true
 		if a.timer == nil {
 			event = sdl.WaitEvent()
@@ -385,7 +429,7 @@ func (a *Area) Run() {
 		if dispetchEvent {
 			a.HandleEvent(event)
 		}
-		if !a.terminate {
+		if !a.terminate && !quitRequested {
 			a.Draw()
 			screen.ShowMouse()
 			screen.Flush()
@@ -987,11 +1031,19 @@ type Slider struct {
 	highlight                bool
 	dragging                 bool
 	dragOffsetX              int32
+	onChange                 SliderOnChangeFunc
 }
 
+type SliderOnChangeFunc func(float32)
+
 func NewSlider(x, y, w, h int32, v *float32) *Slider {
+	return NewSliderCmd(x, y, w, h, v, nil)
+}
+
+func NewSliderCmd(x, y, w, h int32, v *float32, onChange SliderOnChangeFunc) *Slider {
 	s := &Slider{
-		value: v,
+		value:    v,
+		onChange: onChange,
 	}
 	s.left = x
 	s.top = y
@@ -1116,7 +1168,9 @@ func (s *Slider) OnMouseMove(x, y int32) bool {
 		if val != *s.value {
 			*s.value = val
 			s.Draw()
-			sound.SetVolume(*s.value)
+			if s.onChange != nil {
+				s.onChange(*s.value)
+			}
 		}
 		return true
 	}